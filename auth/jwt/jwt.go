@@ -0,0 +1,359 @@
+package jwt
+
+/*
+Signs and verifies compact JWTs (RFC 7519) using HS256 or EdDSA (Ed25519).
+
+A Verifier only accepts the algorithms of the keys it was given, so the
+"alg" header can't be used to downgrade to "none" or to trick us into
+using a public key as an HMAC secret.
+
+Registered claims are validated as follows:
+  - exp, when present, must be in the future
+  - nbf, when present, must be in the past
+  - iat, when present, must not be in the future
+  - iss, when the Verifier has an Issuer, must equal it
+  - aud, when the Verifier has an Audience, must equal or contain it
+
+Time-based checks allow for the configured clock skew.
+*/
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/auth"
+	"src.sqlkite.com/utils/http"
+	"src.sqlkite.com/utils/json"
+	"src.sqlkite.com/utils/log"
+	"src.sqlkite.com/utils/typed"
+)
+
+const (
+	HS256 = "HS256"
+	EdDSA = "EdDSA"
+)
+
+var (
+	ErrMalformed    = errors.New("jwt: malformed token")
+	ErrAlgorithm    = errors.New("jwt: unsupported algorithm")
+	ErrSignature    = errors.New("jwt: invalid signature")
+	ErrExpired      = errors.New("jwt: token expired")
+	ErrNotYetValid  = errors.New("jwt: token not yet valid")
+	ErrIssuedFuture = errors.New("jwt: token issued in the future")
+	ErrIssuer       = errors.New("jwt: invalid issuer")
+	ErrAudience     = errors.New("jwt: invalid audience")
+	ErrCannotSign   = errors.New("jwt: key cannot sign")
+	ErrKeyLength    = errors.New("jwt: HS256 secret must be at least 32 bytes")
+
+	b64 = base64.RawURLEncoding
+)
+
+type Key struct {
+	alg string
+
+	// optional, written to/matched against the "kid" header
+	id string
+
+	secret  []byte
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// The secret must be at least 32 bytes (the size of the hash), as
+// required by RFC 7518 section 3.2
+func HS256Key(id string, secret []byte) (Key, error) {
+	if len(secret) < sha256.Size {
+		return Key{}, ErrKeyLength
+	}
+	return Key{alg: HS256, id: id, secret: secret}, nil
+}
+
+// A key which can both sign and verify
+func EdDSAKey(id string, private ed25519.PrivateKey) Key {
+	return Key{
+		alg:     EdDSA,
+		id:      id,
+		private: private,
+		public:  private.Public().(ed25519.PublicKey),
+	}
+}
+
+// A key which can only verify
+func EdDSAPublicKey(id string, public ed25519.PublicKey) Key {
+	return Key{alg: EdDSA, id: id, public: public}
+}
+
+func (k Key) sign(data []byte) ([]byte, error) {
+	switch k.alg {
+	case HS256:
+		mac := hmac.New(sha256.New, k.secret)
+		mac.Write(data)
+		return mac.Sum(nil), nil
+	case EdDSA:
+		if k.private == nil {
+			return nil, ErrCannotSign
+		}
+		return ed25519.Sign(k.private, data), nil
+	}
+	return nil, ErrAlgorithm
+}
+
+func (k Key) verify(data []byte, signature []byte) bool {
+	switch k.alg {
+	case HS256:
+		expected, _ := k.sign(data)
+		return hmac.Equal(expected, signature)
+	case EdDSA:
+		return len(k.public) == ed25519.PublicKeySize && ed25519.Verify(k.public, data, signature)
+	}
+	return false
+}
+
+type header struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ,omitempty"`
+	Kid string `json:"kid,omitempty"`
+}
+
+func Sign(key Key, claims typed.Typed) (string, error) {
+	h, err := json.Marshal(header{Alg: key.alg, Typ: "JWT", Kid: key.id})
+	if err != nil {
+		return "", err
+	}
+	c, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signed := b64.EncodeToString(h) + "." + b64.EncodeToString(c)
+	signature, err := key.sign(utils.S2B(signed))
+	if err != nil {
+		return "", err
+	}
+	return signed + "." + b64.EncodeToString(signature), nil
+}
+
+type VerifierConfig struct {
+	Issuer   string `json:"issuer"`
+	Audience string `json:"audience"`
+
+	// in seconds, defaults to 60
+	Skew uint16 `json:"skew"`
+}
+
+type Verifier struct {
+	keys     []Key
+	issuer   string
+	audience string
+	skew     time.Duration
+
+	// for tests
+	now func() time.Time
+}
+
+func NewVerifier(config VerifierConfig, keys ...Key) *Verifier {
+	skew := config.Skew
+	if skew == 0 {
+		skew = 60
+	}
+	return &Verifier{
+		keys:     keys,
+		issuer:   config.Issuer,
+		audience: config.Audience,
+		skew:     time.Duration(skew) * time.Second,
+		now:      time.Now,
+	}
+}
+
+// Returns the token's claims when the token is signed by one of our keys
+// and its registered claims are valid.
+func (v *Verifier) Verify(token string) (typed.Typed, error) {
+	dot1 := strings.IndexByte(token, '.')
+	dot2 := strings.LastIndexByte(token, '.')
+	if dot1 == -1 || dot1 == dot2 {
+		return nil, ErrMalformed
+	}
+
+	var h header
+	if err := decodeJson(token[:dot1], &h); err != nil {
+		return nil, ErrMalformed
+	}
+
+	signature, err := b64.DecodeString(token[dot2+1:])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	signed := utils.S2B(token[:dot2])
+	if err := v.verifySignature(h, signed, signature); err != nil {
+		return nil, err
+	}
+
+	var claims typed.Typed
+	if err := decodeJson(token[dot1+1:dot2], &claims); err != nil || claims == nil {
+		return nil, ErrMalformed
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+func (v *Verifier) verifySignature(h header, signed []byte, signature []byte) error {
+	supported := false
+	for _, key := range v.keys {
+		if key.alg != h.Alg {
+			continue
+		}
+		supported = true
+		if h.Kid != "" && key.id != "" && key.id != h.Kid {
+			continue
+		}
+		if key.verify(signed, signature) {
+			return nil
+		}
+	}
+	if !supported {
+		return ErrAlgorithm
+	}
+	return ErrSignature
+}
+
+func (v *Verifier) validate(claims typed.Typed) error {
+	now := v.now()
+
+	exp, ok, err := numericDate(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if ok && !now.Before(exp.Add(v.skew)) {
+		return ErrExpired
+	}
+
+	nbf, ok, err := numericDate(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.skew).Before(nbf) {
+		return ErrNotYetValid
+	}
+
+	iat, ok, err := numericDate(claims, "iat")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.skew).Before(iat) {
+		return ErrIssuedFuture
+	}
+
+	if v.issuer != "" && claims.String("iss") != v.issuer {
+		return ErrIssuer
+	}
+
+	if audience := v.audience; audience != "" {
+		if aud, ok := claims.StringIf("aud"); ok {
+			if aud != audience {
+				return ErrAudience
+			}
+		} else if !contains(claims.Strings("aud"), audience) {
+			return ErrAudience
+		}
+	}
+	return nil
+}
+
+// Verifies the request's bearer token. The returned response is non-nil
+// when the token is missing or invalid.
+func (v *Verifier) FromRequest(conn *fasthttp.RequestCtx) (typed.Typed, http.Response) {
+	token := auth.BearerToken(conn)
+	if token == "" {
+		return nil, auth.Unauthorized
+	}
+	claims, err := v.Verify(token)
+	if err != nil {
+		return nil, unauthorizedResponse{auth.Unauthorized, err}
+	}
+	return claims, nil
+}
+
+// Creates an env loader, for use with http.Handler, which only calls
+// create for requests with a valid token:
+//
+//	http.Handler("user", jwt.LoadEnv(verifier, createEnv), handler)
+func LoadEnv[T http.Env](v *Verifier, create func(conn *fasthttp.RequestCtx, claims typed.Typed) (T, http.Response, error)) func(conn *fasthttp.RequestCtx) (T, http.Response, error) {
+	return func(conn *fasthttp.RequestCtx) (T, http.Response, error) {
+		claims, res := v.FromRequest(conn)
+		if res != nil {
+			var env T
+			return env, res, nil
+		}
+		return create(conn, claims)
+	}
+}
+
+// Adds the reason the token was rejected to the request log. The
+// reason isn't sent to the client.
+type unauthorizedResponse struct {
+	http.StaticResponse
+	err error
+}
+
+func (r unauthorizedResponse) EnhanceLog(logger log.Logger) log.Logger {
+	return r.StaticResponse.EnhanceLog(logger).String("jwt", r.err.Error())
+}
+
+func decodeJson(segment string, dest any) error {
+	data, err := b64.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dest)
+}
+
+// Returns false if the claim isn't present. A claim that is present
+// but isn't a NumericDate (a JSON number of seconds) makes the token
+// malformed, rather than being ignored.
+func numericDate(claims typed.Typed, key string) (time.Time, bool, error) {
+	value, exists := claims[key]
+	if !exists {
+		return time.Time{}, false, nil
+	}
+
+	var seconds float64
+	switch n := value.(type) {
+	case float64:
+		seconds = n
+	case int:
+		seconds = float64(n)
+	case int64:
+		seconds = float64(n)
+	default:
+		return time.Time{}, false, ErrMalformed
+	}
+
+	// anything outside of this can't be represented as a time.Time
+	// (in nanoseconds) without overflowing
+	if math.IsNaN(seconds) || seconds > maxNumericDate || seconds < -maxNumericDate {
+		return time.Time{}, false, ErrMalformed
+	}
+	return time.Unix(0, int64(seconds*float64(time.Second))), true, nil
+}
+
+const maxNumericDate = float64(math.MaxInt64 / int64(time.Second))
+
+func contains(values []string, target string) bool {
+	for _, value := range values {
+		if value == target {
+			return true
+		}
+	}
+	return false
+}
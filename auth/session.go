@@ -0,0 +1,253 @@
+package auth
+
+/*
+Session tokens are a JSON payload (user id, expiry and arbitrary claims)
+sealed with encryption.Encrypt and base64url encoded. They're opaque to
+clients and can't be forged or altered without one of our keys.
+
+The first configured key seals new tokens. Every key is tried when
+opening a token, so rotating keys is a matter of prepending a new key
+and, once existing tokens have expired, removing the old one.
+
+A token is read from an "Authorization: Bearer" header or, when a cookie
+name is configured, from that cookie:
+
+	session, res := sessions.FromRequest(conn)
+	if res != nil {
+		return res, nil
+	}
+*/
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/encryption"
+	"src.sqlkite.com/utils/http"
+	"src.sqlkite.com/utils/json"
+	"src.sqlkite.com/utils/log"
+	"src.sqlkite.com/utils/typed"
+)
+
+var (
+	ErrInvalidSession = errors.New("invalid session")
+	ErrSessionExpired = errors.New("session expired")
+
+	Unauthorized   = http.StaticError(401, utils.RES_UNAUTHORIZED, "unauthorized").Header("WWW-Authenticate", "Bearer")
+	SessionExpired = http.StaticError(401, utils.RES_SESSION_EXPIRED, "session expired").Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+)
+
+type SessionConfig struct {
+	// hex-encoded 32 byte keys. The first is used to seal new tokens.
+	Keys []string `json:"keys"`
+
+	// how long, in seconds, newly sealed tokens are valid for
+	TTL uint32 `json:"ttl"`
+
+	// the cookie to look for the token in, when there's no
+	// Authorization header. Empty disables cookies.
+	Cookie string `json:"cookie"`
+}
+
+type Session struct {
+	UserId  string
+	Expires time.Time
+	Claims  typed.Typed
+}
+
+// the sealed payload; short keys since it ends up in every request
+type sessionPayload struct {
+	UserId  string         `json:"u"`
+	Expires int64          `json:"e"`
+	Claims  map[string]any `json:"c,omitempty"`
+}
+
+type Sessions struct {
+	ttl    time.Duration
+	cookie string
+	keys   [][32]byte
+
+	// for tests
+	now func() time.Time
+}
+
+func NewSessions(config SessionConfig) (*Sessions, error) {
+	if len(config.Keys) == 0 {
+		return nil, log.Errf(utils.ERR_AUTH_KEY, "session config requires at least 1 key")
+	}
+
+	keys := make([][32]byte, len(config.Keys))
+	for i, k := range config.Keys {
+		decoded, err := hex.DecodeString(k)
+		if err != nil {
+			return nil, log.Err(utils.ERR_AUTH_KEY, err).Int("index", i)
+		}
+		if len(decoded) != 32 {
+			return nil, log.Errf(utils.ERR_AUTH_KEY, "session key must be 32 bytes").Int("index", i)
+		}
+		copy(keys[i][:], decoded)
+	}
+
+	ttl := config.TTL
+	if ttl == 0 {
+		ttl = 86400
+	}
+
+	log.Info("session_config").
+		Int("keys", len(keys)).
+		Int("ttl", int(ttl)).
+		String("cookie", config.Cookie).
+		Log()
+
+	return &Sessions{
+		keys:   keys,
+		cookie: config.Cookie,
+		ttl:    time.Duration(ttl) * time.Second,
+		now:    time.Now,
+	}, nil
+}
+
+// Seals a new token for the user which expires after the configured TTL
+func (s *Sessions) Seal(userId string, claims typed.Typed) (string, error) {
+	return s.SealSession(Session{
+		UserId:  userId,
+		Claims:  claims,
+		Expires: s.now().Add(s.ttl),
+	})
+}
+
+func (s *Sessions) SealSession(session Session) (string, error) {
+	data, err := json.Marshal(sessionPayload{
+		UserId:  session.UserId,
+		Claims:  session.Claims,
+		Expires: session.Expires.Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	sealed, err := encryption.Encrypt(s.keys[0], utils.B2S(data))
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Returns ErrInvalidSession if the token can't be decoded or opened
+// with any of our keys and ErrSessionExpired if it has expired.
+func (s *Sessions) Open(token string) (Session, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return Session{}, ErrInvalidSession
+	}
+
+	var data []byte
+	for _, key := range s.keys {
+		var ok bool
+		if data, ok = encryption.Decrypt(key, sealed); ok {
+			break
+		}
+	}
+	if data == nil {
+		return Session{}, ErrInvalidSession
+	}
+
+	var payload sessionPayload
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return Session{}, ErrInvalidSession
+	}
+
+	session := Session{
+		UserId:  payload.UserId,
+		Claims:  typed.New(payload.Claims),
+		Expires: time.Unix(payload.Expires, 0),
+	}
+
+	if !s.now().Before(session.Expires) {
+		return session, ErrSessionExpired
+	}
+	return session, nil
+}
+
+// Opens the token found in the request. The returned response is
+// non-nil when there's no valid session.
+func (s *Sessions) FromRequest(conn *fasthttp.RequestCtx) (Session, http.Response) {
+	token := BearerToken(conn)
+	if token == "" && s.cookie != "" {
+		token = CookieToken(conn, s.cookie)
+	}
+	if token == "" {
+		return Session{}, Unauthorized
+	}
+
+	session, err := s.Open(token)
+	if err == nil {
+		return session, nil
+	}
+	if err == ErrSessionExpired {
+		return Session{}, SessionExpired
+	}
+	return Session{}, Unauthorized
+}
+
+// A cookie holding the token, expiring with it. Requires a configured
+// cookie name.
+func (s *Sessions) Cookie(token string) *fasthttp.Cookie {
+	cookie := s.baseCookie()
+	cookie.SetValue(token)
+	cookie.SetExpire(s.now().Add(s.ttl))
+	return cookie
+}
+
+// A cookie which removes the session cookie from the client
+func (s *Sessions) ClearCookie() *fasthttp.Cookie {
+	cookie := s.baseCookie()
+	cookie.SetExpire(fasthttp.CookieExpireDelete)
+	return cookie
+}
+
+func (s *Sessions) baseCookie() *fasthttp.Cookie {
+	cookie := &fasthttp.Cookie{}
+	cookie.SetKey(s.cookie)
+	cookie.SetPath("/")
+	cookie.SetHTTPOnly(true)
+	cookie.SetSecure(true)
+	cookie.SetSameSite(fasthttp.CookieSameSiteLaxMode)
+	return cookie
+}
+
+// The token from an "Authorization: Bearer <token>" header, or ""
+func BearerToken(conn *fasthttp.RequestCtx) string {
+	value := conn.Request.Header.Peek("Authorization")
+	// the scheme is case-insensitive
+	if len(value) < 8 || (value[6] != ' ') || !equalFoldBearer(value[:6]) {
+		return ""
+	}
+
+	token := value[7:]
+	for len(token) > 0 && token[0] == ' ' {
+		token = token[1:]
+	}
+	return string(token)
+}
+
+func CookieToken(conn *fasthttp.RequestCtx, name string) string {
+	return string(conn.Request.Header.Cookie(name))
+}
+
+func equalFoldBearer(scheme []byte) bool {
+	const bearer = "bearer"
+	for i, c := range scheme {
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		if c != bearer[i] {
+			return false
+		}
+	}
+	return true
+}
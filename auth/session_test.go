@@ -0,0 +1,194 @@
+package auth
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/http"
+	"src.sqlkite.com/utils/http/httptest"
+	"src.sqlkite.com/utils/log"
+	"src.sqlkite.com/utils/typed"
+)
+
+func Test_NewSessions_InvalidKeys(t *testing.T) {
+	_, err := NewSessions(SessionConfig{})
+	assert.Equal(t, err.(*log.StructuredError).Code, 3009)
+
+	_, err = NewSessions(SessionConfig{Keys: []string{"nope"}})
+	assert.Equal(t, err.(*log.StructuredError).Code, 3009)
+
+	_, err = NewSessions(SessionConfig{Keys: []string{"aabbcc"}})
+	assert.Equal(t, err.(*log.StructuredError).Code, 3009)
+}
+
+func Test_Sessions_SealAndOpen(t *testing.T) {
+	sessions := testSessions(t, 60, randomKey())
+	token, err := sessions.Seal("leto", typed.Typed{"role": "admin"})
+	assert.Nil(t, err)
+
+	session, err := sessions.Open(token)
+	assert.Nil(t, err)
+	assert.Equal(t, session.UserId, "leto")
+	assert.Equal(t, session.Claims.String("role"), "admin")
+	assert.Equal(t, session.Expires.Unix(), sessions.now().Add(time.Minute).Unix())
+}
+
+func Test_Sessions_Open_Invalid(t *testing.T) {
+	sessions := testSessions(t, 60, randomKey())
+	token, _ := sessions.Seal("leto", nil)
+
+	for _, invalid := range []string{"", "!!!", "AAAA", token[:len(token)-2], strings.ToUpper(token)} {
+		_, err := sessions.Open(invalid)
+		assert.True(t, err == ErrInvalidSession)
+	}
+
+	// sealed with a key we don't know
+	other := testSessions(t, 60, randomKey())
+	_, err := other.Open(token)
+	assert.True(t, err == ErrInvalidSession)
+}
+
+func Test_Sessions_Open_Expired(t *testing.T) {
+	sessions := testSessions(t, 60, randomKey())
+	token, _ := sessions.Seal("leto", nil)
+
+	sessions.now = func() time.Time { return time.Now().Add(59 * time.Second) }
+	_, err := sessions.Open(token)
+	assert.Nil(t, err)
+
+	sessions.now = func() time.Time { return time.Now().Add(61 * time.Second) }
+	session, err := sessions.Open(token)
+	assert.True(t, err == ErrSessionExpired)
+	assert.Equal(t, session.UserId, "leto")
+}
+
+func Test_Sessions_KeyRotation(t *testing.T) {
+	oldKey, newKey := randomKey(), randomKey()
+	old := testSessions(t, 60, oldKey)
+	token, _ := old.Seal("leto", nil)
+
+	rotated := testSessions(t, 60, newKey, oldKey)
+	session, err := rotated.Open(token)
+	assert.Nil(t, err)
+	assert.Equal(t, session.UserId, "leto")
+
+	// new tokens are sealed with the first key
+	token, _ = rotated.Seal("duncan", nil)
+	_, err = old.Open(token)
+	assert.True(t, err == ErrInvalidSession)
+	session, err = testSessions(t, 60, newKey).Open(token)
+	assert.Nil(t, err)
+	assert.Equal(t, session.UserId, "duncan")
+}
+
+func Test_BearerToken(t *testing.T) {
+	assert.Equal(t, BearerToken(httptest.Request().Conn()), "")
+	for header, expected := range map[string]string{
+		"Bearer abc123":  "abc123",
+		"bearer abc123":  "abc123",
+		"BEARER  abc123": "abc123",
+		"Bearer ":        "",
+		"Basic abc123":   "",
+		"Bearerabc123":   "",
+		"abc123":         "",
+	} {
+		conn := httptest.Request().Header("Authorization", header).Conn()
+		assert.Equal(t, BearerToken(conn), expected)
+	}
+}
+
+func Test_Sessions_FromRequest(t *testing.T) {
+	sessions := testSessions(t, 60, randomKey())
+	sessions.cookie = "sid"
+	token, _ := sessions.Seal("leto", nil)
+
+	// missing
+	_, res := sessions.FromRequest(httptest.Request().Conn())
+	assertUnauthorized(t, res, 2005)
+
+	// invalid
+	_, res = sessions.FromRequest(httptest.Request().Header("Authorization", "Bearer nope").Conn())
+	assertUnauthorized(t, res, 2005)
+
+	// bearer
+	session, res := sessions.FromRequest(httptest.Request().Header("Authorization", "Bearer "+token).Conn())
+	assert.Nil(t, res)
+	assert.Equal(t, session.UserId, "leto")
+
+	// cookie
+	session, res = sessions.FromRequest(httptest.Request().Header("Cookie", "sid="+token).Conn())
+	assert.Nil(t, res)
+	assert.Equal(t, session.UserId, "leto")
+
+	// expired
+	sessions.now = func() time.Time { return time.Now().Add(time.Hour) }
+	_, res = sessions.FromRequest(httptest.Request().Header("Authorization", "Bearer "+token).Conn())
+	assertUnauthorized(t, res, 2012)
+}
+
+func Test_Sessions_FromRequest_NoCookie(t *testing.T) {
+	sessions := testSessions(t, 60, randomKey())
+	token, _ := sessions.Seal("leto", nil)
+	_, res := sessions.FromRequest(httptest.Request().Header("Cookie", "sid="+token).Conn())
+	assertUnauthorized(t, res, 2005)
+}
+
+func Test_Sessions_Cookie(t *testing.T) {
+	sessions := testSessions(t, 60, randomKey())
+	sessions.cookie = "sid"
+
+	cookie := sessions.Cookie("abc")
+	assert.Equal(t, string(cookie.Key()), "sid")
+	assert.Equal(t, string(cookie.Value()), "abc")
+	assert.Equal(t, string(cookie.Path()), "/")
+	assert.True(t, cookie.HTTPOnly())
+	assert.True(t, cookie.Secure())
+	assert.Equal(t, cookie.SameSite(), fasthttp.CookieSameSiteLaxMode)
+	assert.Equal(t, cookie.Expire().Unix(), sessions.now().Add(time.Minute).Unix())
+
+	cookie = sessions.ClearCookie()
+	assert.Equal(t, string(cookie.Key()), "sid")
+	assert.Equal(t, len(cookie.Value()), 0)
+	assert.True(t, cookie.Expire().Equal(fasthttp.CookieExpireDelete))
+}
+
+func assertUnauthorized(t *testing.T, res http.Response, code int) {
+	t.Helper()
+	conn := &fasthttp.RequestCtx{}
+	res.Write(conn)
+	r := httptest.NewResponse(conn, "")
+	assert.Equal(t, r.Status, 401)
+	assert.Equal(t, typed.Must([]byte(r.Body)).Int("code"), code)
+	assert.True(t, strings.HasPrefix(r.Header("WWW-Authenticate"), "Bearer"))
+}
+
+func testSessions(t *testing.T, ttl uint32, keys ...[32]byte) *Sessions {
+	t.Helper()
+	hexKeys := make([]string, len(keys))
+	for i, key := range keys {
+		hexKeys[i] = hex.EncodeToString(key[:])
+	}
+
+	var sessions *Sessions
+	var err error
+	tests.CaptureLog(func() {
+		sessions, err = NewSessions(SessionConfig{Keys: hexKeys, TTL: ttl})
+	})
+	assert.Nil(t, err)
+
+	now := time.Now()
+	sessions.now = func() time.Time { return now }
+	return sessions
+}
+
+func randomKey() [32]byte {
+	var key [32]byte
+	rand.Read(key[:])
+	return key
+}
@@ -20,14 +20,58 @@ const (
 	VAL_FLOAT_MIN          = 1017
 	VAL_FLOAT_MAX          = 1019
 	VAL_FLOAT_RANGE        = 1020
+	VAL_ARRAY_UNIQUE       = 1021
+	VAL_FIELD_EQUAL        = 1022
+	VAL_FIELD_GREATER      = 1023
+	VAL_FIELDS_EXCLUSIVE   = 1024
+	VAL_FIELDS_ONE_OF      = 1025
+	VAL_UNKNOWN_FIELD      = 1026
+	VAL_STRING_EMAIL       = 1027
+	VAL_STRING_URL         = 1028
+	VAL_STRING_HOSTNAME    = 1029
+	VAL_STRING_IP          = 1030
+	VAL_STRING_CIDR        = 1031
+	VAL_STRING_DATE        = 1032
+	VAL_STRING_DATETIME    = 1033
+	VAL_STRING_BASE64      = 1034
+	VAL_STRING_HEX         = 1035
+	VAL_STRING_SLUG        = 1036
+	VAL_TIME_TYPE          = 1037
+	VAL_TIME_BEFORE        = 1038
+	VAL_TIME_AFTER         = 1039
+	VAL_TIME_RANGE         = 1040
+	VAL_DURATION_TYPE      = 1041
+	VAL_DURATION_MIN       = 1042
+	VAL_DURATION_MAX       = 1043
+	VAL_DURATION_RANGE     = 1044
+	VAL_MAP_TYPE           = 1045
+	VAL_MAP_MIN_KEYS       = 1046
+	VAL_MAP_MAX_KEYS       = 1047
+	VAL_MAP_RANGE_KEYS     = 1048
+	VAL_MAP_DUPLICATE_KEY  = 1049
 
 	RES_SERVER_ERROR         = 2001
 	RES_SERIALIZATION_ERROR  = 2002
 	RES_INVALID_JSON_PAYLOAD = 2003
 	RES_VALIDATION           = 2004
+	RES_UNAUTHORIZED         = 2005
+	RES_FORBIDDEN            = 2006
+	RES_CONFLICT             = 2007
+	RES_TOO_MANY_REQUESTS    = 2008
+	RES_PAYLOAD_TOO_LARGE    = 2009
+	RES_METHOD_NOT_ALLOWED   = 2010
+	RES_TIMEOUT              = 2011
+	RES_SESSION_EXPIRED      = 2012
 
 	ERR_INVALID_LOG_LEVEL  = 3001
 	ERR_INVALID_LOG_FORMAT = 3002
 	ERR_PG_INIT            = 3003
 	ERR_SQLITE_INIT        = 3004
+	ERR_HTTP_LISTEN        = 3005
+	ERR_HTTP_SERVE         = 3006
+	ERR_HTTP_SHUTDOWN      = 3007
+	ERR_HTTP_TRUSTED_PROXY = 3008
+	ERR_AUTH_KEY           = 3009
+	ERR_PASSWORD_CONFIG    = 3010
+	ERR_HTTP_CORS          = 3011
 )
@@ -37,6 +37,21 @@ func (m Map[V]) Put(id string, value V) {
 	m.shard(id).put(id, value)
 }
 
+func (m Map[V]) Delete(id string) {
+	m.shard(id).delete(id)
+}
+
+// Deletes every entry for which fn returns true. Each shard is locked
+// while being scanned, so fn should be cheap. Returns the number of
+// entries deleted.
+func (m Map[V]) DeleteIf(fn func(id string, value V) bool) int {
+	deleted := 0
+	for _, shard := range m.shards {
+		deleted += shard.deleteIf(fn)
+	}
+	return deleted
+}
+
 func (m Map[V]) shard(id string) *shard[V] {
 	var h uint32
 	for i := 0; i < len(id); i++ {
@@ -87,3 +102,22 @@ func (s *shard[V]) put(id string, value V) {
 	s.lookup[id] = value
 	s.Unlock()
 }
+
+func (s *shard[V]) delete(id string) {
+	s.Lock()
+	delete(s.lookup, id)
+	s.Unlock()
+}
+
+func (s *shard[V]) deleteIf(fn func(id string, value V) bool) int {
+	deleted := 0
+	s.Lock()
+	for id, value := range s.lookup {
+		if fn(id, value) {
+			delete(s.lookup, id)
+			deleted += 1
+		}
+	}
+	s.Unlock()
+	return deleted
+}
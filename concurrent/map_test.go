@@ -66,3 +66,41 @@ func Test_Put(t *testing.T) {
 	actual, _ = m.Get("i1")
 	assert.Equal(t, actual, ti2)
 }
+
+func Test_Delete(t *testing.T) {
+	loads := 0
+	m := NewMap[*TestItem](func(id string) (*TestItem, error) {
+		loads += 1
+		return &TestItem{id: id}, nil
+	})
+
+	m.Get("i1")
+	m.Delete("i1")
+	m.Delete("i2") // noop
+	assert.Equal(t, loads, 1)
+
+	// deleted, so it has to be reloaded
+	actual, _ := m.Get("i1")
+	assert.Equal(t, actual.id, "i1")
+	assert.Equal(t, loads, 2)
+}
+
+func Test_DeleteIf(t *testing.T) {
+	m := NewMap[*TestItem](nil)
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		m.Put(id, &TestItem{id: id})
+	}
+
+	deleted := m.DeleteIf(func(id string, value *TestItem) bool {
+		n, _ := strconv.Atoi(value.id)
+		return n%2 == 0
+	})
+	assert.Equal(t, deleted, 50)
+
+	for i := 0; i < 100; i++ {
+		id := strconv.Itoa(i)
+		_, exists := m.shard(id).lookup[id]
+		assert.Equal(t, exists, i%2 == 1)
+	}
+}
@@ -0,0 +1,132 @@
+package encryption
+
+/*
+XChaCha20-Poly1305 with associated data. The associated data (e.g. a
+project id and column name) isn't stored in the ciphertext but must be
+given, unchanged, to decrypt it. This binds a ciphertext to its context:
+a value copied to another row or column won't decrypt.
+
+AEAD uses a random nonce, so encrypting the same value twice gives two
+different ciphertexts. Deterministic derives the nonce from the key,
+associated data and plain text (SIV-style), so the same inputs always
+give the same ciphertext, which makes it possible to look up (or enforce
+uniqueness on) an encrypted column. It leaks which values are equal, so
+it should only be used for columns that need it.
+
+Both write: [nonce (24)][ciphertext][tag (16)]
+*/
+
+import (
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const aeadOverhead = chacha20poly1305.NonceSizeX + chacha20poly1305.Overhead
+
+type AEAD struct {
+	aead cipher.AEAD
+}
+
+func NewAEAD(key [32]byte) *AEAD {
+	return &AEAD{aead: newXChaCha(key[:])}
+}
+
+// Appends the nonce and the encrypted plainText to dst, which should
+// have at least 40 + len(plainText) bytes of spare capacity to avoid
+// allocating.
+func (a *AEAD) Seal(dst []byte, plainText []byte, ad []byte) ([]byte, error) {
+	var nonce [chacha20poly1305.NonceSizeX]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
+		return nil, err
+	}
+	dst = append(dst, nonce[:]...)
+	return a.aead.Seal(dst, nonce[:], plainText, ad), nil
+}
+
+// Appends the decrypted value to dst. Returns ErrTooShort or
+// ErrAuthFailed (which includes the associated data not matching).
+func (a *AEAD) Open(dst []byte, encrypted []byte, ad []byte) ([]byte, error) {
+	return openXChaCha(a.aead, dst, encrypted, ad)
+}
+
+type Deterministic struct {
+	aead   cipher.AEAD
+	macKey []byte
+}
+
+// Separate encryption and nonce keys are derived from key, so the same
+// key can safely be used with both NewAEAD and NewDeterministic.
+func NewDeterministic(key [32]byte) *Deterministic {
+	kdf := hkdf.New(sha256.New, key[:], nil, []byte("sqlkite deterministic"))
+	var keys [64]byte
+	if _, err := io.ReadFull(kdf, keys[:]); err != nil {
+		// can only fail when reading more than 255 * 32 bytes
+		panic(err)
+	}
+	return &Deterministic{
+		aead:   newXChaCha(keys[:32]),
+		macKey: keys[32:],
+	}
+}
+
+// Like AEAD.Seal, but the nonce is an HMAC of the associated data and
+// plainText.
+func (d *Deterministic) Seal(dst []byte, plainText []byte, ad []byte) []byte {
+	nonce := d.nonce(plainText, ad)
+	dst = append(dst, nonce...)
+	return d.aead.Seal(dst, nonce, plainText, ad)
+}
+
+// Like AEAD.Open, but also checks that the nonce is the one Seal would
+// have generated.
+func (d *Deterministic) Open(dst []byte, encrypted []byte, ad []byte) ([]byte, error) {
+	l := len(dst)
+	out, err := openXChaCha(d.aead, dst, encrypted, ad)
+	if err != nil {
+		return nil, err
+	}
+	if !hmac.Equal(d.nonce(out[l:], ad), encrypted[:chacha20poly1305.NonceSizeX]) {
+		return nil, ErrAuthFailed
+	}
+	return out, nil
+}
+
+func (d *Deterministic) nonce(plainText []byte, ad []byte) []byte {
+	mac := hmac.New(sha256.New, d.macKey)
+	// length-prefix the associated data so that ("ab", "c") and ("a", "bc")
+	// don't produce the same nonce
+	var l [8]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(ad)))
+	mac.Write(l[:])
+	mac.Write(ad)
+	mac.Write(plainText)
+	return mac.Sum(nil)[:chacha20poly1305.NonceSizeX]
+}
+
+func newXChaCha(key []byte) cipher.AEAD {
+	aead, err := chacha20poly1305.NewX(key)
+	if err != nil {
+		// only fails on an invalid key size
+		panic(err)
+	}
+	return aead
+}
+
+func openXChaCha(aead cipher.AEAD, dst []byte, encrypted []byte, ad []byte) ([]byte, error) {
+	if len(encrypted) < aeadOverhead {
+		return nil, ErrTooShort
+	}
+	nonce := encrypted[:chacha20poly1305.NonceSizeX]
+	out, err := aead.Open(dst, nonce, encrypted[chacha20poly1305.NonceSizeX:], ad)
+	if err != nil {
+		return nil, ErrAuthFailed
+	}
+	return out, nil
+}
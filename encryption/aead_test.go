@@ -0,0 +1,102 @@
+package encryption
+
+import (
+	"testing"
+
+	"src.sqlkite.com/tests/assert"
+)
+
+func Test_AEAD_RoundTrip(t *testing.T) {
+	aead := NewAEAD(randomKey())
+	ad := []byte("project:1/users.ssn")
+
+	encrypted, err := aead.Seal(nil, []byte("it's over 9000!!"), ad)
+	assert.Nil(t, err)
+	assert.Equal(t, len(encrypted), 40+16)
+
+	plain, err := aead.Open(nil, encrypted, ad)
+	assert.Nil(t, err)
+	assert.Equal(t, string(plain), "it's over 9000!!")
+
+	// random nonce
+	other, _ := aead.Seal(nil, []byte("it's over 9000!!"), ad)
+	assert.NotEqual(t, string(other), string(encrypted))
+}
+
+func Test_AEAD_AssociatedData(t *testing.T) {
+	aead := NewAEAD(randomKey())
+	encrypted, _ := aead.Seal(nil, []byte("hello"), []byte("row:1"))
+
+	_, err := aead.Open(nil, encrypted, []byte("row:2"))
+	assert.True(t, err == ErrAuthFailed)
+	_, err = aead.Open(nil, encrypted, nil)
+	assert.True(t, err == ErrAuthFailed)
+
+	// different key
+	_, err = NewAEAD(randomKey()).Open(nil, encrypted, []byte("row:1"))
+	assert.True(t, err == ErrAuthFailed)
+
+	_, err = aead.Open(nil, encrypted[:39], []byte("row:1"))
+	assert.True(t, err == ErrTooShort)
+}
+
+func Test_AEAD_Append(t *testing.T) {
+	aead := NewAEAD(randomKey())
+	encrypted, _ := aead.Seal([]byte("x"), []byte("hello"), nil)
+	assert.Equal(t, encrypted[0], 'x')
+
+	plain, err := aead.Open([]byte(">"), encrypted[1:], nil)
+	assert.Nil(t, err)
+	assert.Equal(t, string(plain), ">hello")
+}
+
+func Test_Deterministic_RoundTrip(t *testing.T) {
+	key := randomKey()
+	d := NewDeterministic(key)
+
+	encrypted := d.Seal(nil, []byte("leto@sqlkite.com"), []byte("users.email"))
+	plain, err := d.Open(nil, encrypted, []byte("users.email"))
+	assert.Nil(t, err)
+	assert.Equal(t, string(plain), "leto@sqlkite.com")
+
+	// same inputs, same output, including across instances
+	assert.Bytes(t, NewDeterministic(key).Seal(nil, []byte("leto@sqlkite.com"), []byte("users.email")), encrypted)
+
+	// different value or associated data, different output
+	assert.NotEqual(t, string(d.Seal(nil, []byte("ghanima@sqlkite.com"), []byte("users.email"))), string(encrypted))
+	assert.NotEqual(t, string(d.Seal(nil, []byte("leto@sqlkite.com"), []byte("users.backup"))), string(encrypted))
+
+	// the associated data is length-prefixed
+	assert.NotEqual(t, string(d.Seal(nil, []byte("bc"), []byte("a"))), string(d.Seal(nil, []byte("c"), []byte("ab"))))
+
+	_, err = d.Open(nil, encrypted, []byte("users.backup"))
+	assert.True(t, err == ErrAuthFailed)
+
+	// a ciphertext from AEAD with the same key doesn't open
+	random, _ := NewAEAD(key).Seal(nil, []byte("leto@sqlkite.com"), []byte("users.email"))
+	_, err = d.Open(nil, random, []byte("users.email"))
+	assert.True(t, err == ErrAuthFailed)
+}
+
+func Test_Encodings(t *testing.T) {
+	encrypted, _ := NewAEAD(randomKey()).Seal(nil, []byte("hello"), nil)
+
+	b := EncodeBase64(encrypted)
+	decoded, err := DecodeBase64(b)
+	assert.Nil(t, err)
+	assert.Bytes(t, decoded, encrypted)
+
+	h := EncodeHex(encrypted)
+	assert.Equal(t, len(h), len(encrypted)*2)
+	decoded, err = DecodeHex(h)
+	assert.Nil(t, err)
+	assert.Bytes(t, decoded, encrypted)
+
+	assert.Equal(t, EncodeBase64([]byte{0xfb, 0xff}), "+/8")
+	assert.Equal(t, EncodeHex([]byte{0xfb, 0xff}), "fbff")
+
+	_, err = DecodeBase64("!!")
+	assert.NotNil(t, err)
+	_, err = DecodeHex("zz")
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,26 @@
+package encryption
+
+// Text encodings for storing ciphertexts in text columns.
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Standard base64, without padding
+func EncodeBase64(encrypted []byte) string {
+	return base64.RawStdEncoding.EncodeToString(encrypted)
+}
+
+func DecodeBase64(encoded string) ([]byte, error) {
+	return base64.RawStdEncoding.DecodeString(encoded)
+}
+
+// Lowercase hex
+func EncodeHex(encrypted []byte) string {
+	return hex.EncodeToString(encrypted)
+}
+
+func DecodeHex(encoded string) ([]byte, error) {
+	return hex.DecodeString(encoded)
+}
@@ -2,23 +2,60 @@ package encryption
 
 import (
 	"crypto/rand"
+	"errors"
 	"io"
 
 	"golang.org/x/crypto/nacl/secretbox"
 	"src.sqlkite.com/utils"
 )
 
+const (
+	nonceLength = 24
+
+	// the smallest possible ciphertext: a nonce and an empty secretbox
+	minLength = nonceLength + secretbox.Overhead
+)
+
+var (
+	ErrTooShort       = errors.New("encryption: ciphertext too short")
+	ErrAuthFailed     = errors.New("encryption: authentication failed")
+	ErrUnknownVersion = errors.New("encryption: unknown version")
+	ErrUnknownKey     = errors.New("encryption: unknown key")
+)
+
 func Encrypt(key [32]byte, plainText string) ([]byte, error) {
-	var nonce [24]byte
-	nonceSlice := nonce[:]
-	if _, err := io.ReadFull(rand.Reader, nonceSlice); err != nil {
+	return EncryptTo(make([]byte, 0, minLength+len(plainText)), key, utils.S2B(plainText))
+}
+
+// Appends the nonce and the encrypted plainText to dst, which should
+// have at least 40 + len(plainText) bytes of spare capacity to avoid
+// allocating. dst and plainText must not overlap.
+func EncryptTo(dst []byte, key [32]byte, plainText []byte) ([]byte, error) {
+	var nonce [nonceLength]byte
+	if _, err := io.ReadFull(rand.Reader, nonce[:]); err != nil {
 		return nil, err
 	}
-	encrypted := secretbox.Seal(nonceSlice, utils.S2B(plainText), &nonce, &key)
-	return encrypted, nil
+	dst = append(dst, nonce[:]...)
+	return secretbox.Seal(dst, plainText, &nonce, &key), nil
 }
 
+// Returns false if encrypted is malformed or can't be authenticated.
+// Use DecryptTo to know why.
 func Decrypt(key [32]byte, encrypted []byte) ([]byte, bool) {
-	nonce := (*[24]byte)(encrypted)
-	return secretbox.Open(nil, encrypted[24:], nonce, &key)
+	plainText, err := DecryptTo(nil, key, encrypted)
+	return plainText, err == nil
+}
+
+// Appends the decrypted value to dst. Returns ErrTooShort or
+// ErrAuthFailed when encrypted can't be decrypted.
+func DecryptTo(dst []byte, key [32]byte, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < minLength {
+		return nil, ErrTooShort
+	}
+	nonce := (*[nonceLength]byte)(encrypted)
+	plainText, ok := secretbox.Open(dst, encrypted[nonceLength:], nonce, &key)
+	if !ok {
+		return nil, ErrAuthFailed
+	}
+	return plainText, nil
 }
@@ -17,3 +17,60 @@ func Test_Encrypt_Decrypt(t *testing.T) {
 	assert.True(t, ok)
 	assert.Equal(t, string(plain), "it's over 9000!!")
 }
+
+func Test_Decrypt_TooShort(t *testing.T) {
+	var key [32]byte
+	rand.Read(key[:])
+
+	for _, l := range []int{0, 1, 23, 24, 39} {
+		_, ok := Decrypt(key, make([]byte, l))
+		assert.False(t, ok)
+		_, err := DecryptTo(nil, key, make([]byte, l))
+		assert.True(t, err == ErrTooShort)
+	}
+
+	// empty plain text is the smallest valid value
+	value, _ := Encrypt(key, "")
+	assert.Equal(t, len(value), 40)
+	plain, err := DecryptTo(nil, key, value)
+	assert.Nil(t, err)
+	assert.Equal(t, len(plain), 0)
+}
+
+func Test_Decrypt_AuthFailed(t *testing.T) {
+	var key, other [32]byte
+	rand.Read(key[:])
+	rand.Read(other[:])
+
+	value, _ := Encrypt(key, "it's over 9000!!")
+	_, err := DecryptTo(nil, other, value)
+	assert.True(t, err == ErrAuthFailed)
+
+	for i := range value {
+		tampered := append([]byte{}, value...)
+		tampered[i] ^= 1
+		_, err := DecryptTo(nil, key, tampered)
+		assert.True(t, err == ErrAuthFailed)
+	}
+}
+
+func Test_EncryptTo_DecryptTo_Append(t *testing.T) {
+	var key [32]byte
+	rand.Read(key[:])
+
+	buf := make([]byte, 0, 100)
+	buf = append(buf, "prefix"...)
+	value, err := EncryptTo(buf, key, []byte("hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(value), 6+40+5)
+	assert.Equal(t, string(value[:6]), "prefix")
+	// used the spare capacity rather than allocating
+	assert.True(t, &value[0] == &buf[:1][0])
+
+	out := make([]byte, 0, 10)
+	out = append(out, '>')
+	plain, err := DecryptTo(out, key, value[6:])
+	assert.Nil(t, err)
+	assert.Equal(t, string(plain), ">hello")
+	assert.True(t, &plain[0] == &out[0])
+}
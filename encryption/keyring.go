@@ -0,0 +1,148 @@
+package encryption
+
+/*
+A Keyring holds multiple keys, each with an id, one of which is the
+primary key used to encrypt. Ciphertexts are prefixed with a version
+byte and the id of the key that encrypted them:
+
+	[version][key id][nonce (24)][secretbox]
+
+so Decrypt knows which key to use and keys can be rotated without
+re-encrypting everything at once: add the new key, make it the primary,
+and migrate stored values with ReEncrypt at leisure. Once nothing
+references the old key, it can be removed.
+
+Values encrypted with the package-level Encrypt have no header. They
+can be migrated with ReEncryptLegacy, given the key they were encrypted
+with.
+*/
+
+import (
+	"errors"
+
+	"src.sqlkite.com/utils"
+)
+
+const (
+	keyringVersion = 1
+	keyringHeader  = 2
+)
+
+var (
+	ErrNoPrimaryKey = errors.New("encryption: primary key not in keyring")
+)
+
+type Keyring struct {
+	primary uint8
+	keys    [256]*[32]byte
+}
+
+func NewKeyring(primary uint8, keys map[uint8][32]byte) (*Keyring, error) {
+	kr := &Keyring{primary: primary}
+	for id, key := range keys {
+		key := key
+		kr.keys[id] = &key
+	}
+	if kr.keys[primary] == nil {
+		return nil, ErrNoPrimaryKey
+	}
+	return kr, nil
+}
+
+func (kr *Keyring) Primary() uint8 {
+	return kr.primary
+}
+
+// Encrypts using the primary key
+func (kr *Keyring) Encrypt(plainText string) ([]byte, error) {
+	return kr.EncryptTo(make([]byte, 0, keyringHeader+minLength+len(plainText)), utils.S2B(plainText))
+}
+
+// Like EncryptTo, but prefixed with our header, so dst should have at
+// least 42 + len(plainText) bytes of spare capacity to avoid allocating.
+func (kr *Keyring) EncryptTo(dst []byte, plainText []byte) ([]byte, error) {
+	dst = append(dst, keyringVersion, kr.primary)
+	return EncryptTo(dst, *kr.keys[kr.primary], plainText)
+}
+
+// Decrypts using the key identified in the ciphertext. Returns false if
+// the ciphertext is malformed, the key isn't in the keyring or the
+// ciphertext can't be authenticated. Use DecryptTo to know why.
+func (kr *Keyring) Decrypt(encrypted []byte) ([]byte, bool) {
+	plainText, err := kr.DecryptTo(nil, encrypted)
+	return plainText, err == nil
+}
+
+// Appends the decrypted value to dst. Returns ErrTooShort,
+// ErrUnknownVersion, ErrUnknownKey or ErrAuthFailed when encrypted
+// can't be decrypted.
+func (kr *Keyring) DecryptTo(dst []byte, encrypted []byte) ([]byte, error) {
+	if len(encrypted) < keyringHeader {
+		return nil, ErrTooShort
+	}
+	if encrypted[0] != keyringVersion {
+		return nil, ErrUnknownVersion
+	}
+	key := kr.keys[encrypted[1]]
+	if key == nil {
+		return nil, ErrUnknownKey
+	}
+	return DecryptTo(dst, *key, encrypted[keyringHeader:])
+}
+
+// The id of the key which encrypted the ciphertext
+func KeyId(encrypted []byte) (uint8, bool) {
+	if len(encrypted) < keyringHeader || encrypted[0] != keyringVersion {
+		return 0, false
+	}
+	return encrypted[1], true
+}
+
+// Re-encrypts the ciphertext with the primary key. Returns the ciphertext
+// as-is (and false) when it's already encrypted with the primary key, so
+// that callers only need to write back changed values.
+func (kr *Keyring) ReEncrypt(encrypted []byte) ([]byte, bool, error) {
+	if id, ok := KeyId(encrypted); ok && id == kr.primary {
+		return encrypted, false, nil
+	}
+
+	plainText, err := kr.DecryptTo(nil, encrypted)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return kr.reEncrypt(plainText, len(encrypted))
+}
+
+// Re-encrypts a value encrypted with the package-level Encrypt (which
+// has no version or key id) using the primary key. Values that are
+// already in the keyring's format are handled like ReEncrypt, so a
+// partially migrated column can safely be processed again.
+//
+// A legacy ciphertext's random nonce can look like our header, so the
+// format is decided by which one authenticates, not by the header.
+func (kr *Keyring) ReEncryptLegacy(key [32]byte, encrypted []byte) ([]byte, bool, error) {
+	id, isKeyring := KeyId(encrypted)
+	if isKeyring {
+		if plainText, err := kr.DecryptTo(nil, encrypted); err == nil {
+			if id == kr.primary {
+				return encrypted, false, nil
+			}
+			return kr.reEncrypt(plainText, len(encrypted))
+		}
+	}
+
+	plainText, err := DecryptTo(nil, key, encrypted)
+	if err != nil {
+		return nil, false, err
+	}
+	return kr.reEncrypt(plainText, len(encrypted)+keyringHeader)
+}
+
+func (kr *Keyring) reEncrypt(plainText []byte, capacity int) ([]byte, bool, error) {
+	reEncrypted, err := kr.EncryptTo(make([]byte, 0, capacity), plainText)
+	if err != nil {
+		return nil, false, err
+	}
+	return reEncrypted, true, nil
+}
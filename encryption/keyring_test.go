@@ -0,0 +1,185 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"testing"
+
+	"golang.org/x/crypto/nacl/secretbox"
+	"src.sqlkite.com/tests/assert"
+)
+
+func Test_NewKeyring_NoPrimary(t *testing.T) {
+	_, err := NewKeyring(2, map[uint8][32]byte{1: randomKey()})
+	assert.True(t, err == ErrNoPrimaryKey)
+}
+
+func Test_Keyring_Encrypt_Decrypt(t *testing.T) {
+	kr, err := NewKeyring(3, map[uint8][32]byte{3: randomKey()})
+	assert.Nil(t, err)
+	assert.Equal(t, kr.Primary(), 3)
+
+	encrypted, err := kr.Encrypt("it's over 9000!!")
+	assert.Nil(t, err)
+	assert.Equal(t, encrypted[0], 1)
+	assert.Equal(t, encrypted[1], 3)
+
+	id, ok := KeyId(encrypted)
+	assert.True(t, ok)
+	assert.Equal(t, id, 3)
+
+	plain, ok := kr.Decrypt(encrypted)
+	assert.True(t, ok)
+	assert.Equal(t, string(plain), "it's over 9000!!")
+}
+
+func Test_Keyring_Decrypt_Invalid(t *testing.T) {
+	kr, _ := NewKeyring(1, map[uint8][32]byte{1: randomKey()})
+	encrypted, _ := kr.Encrypt("hello")
+
+	for _, invalid := range [][]byte{nil, {}, {1}, {1, 1}, encrypted[:20], append([]byte{2}, encrypted[1:]...)} {
+		_, ok := kr.Decrypt(invalid)
+		assert.False(t, ok)
+	}
+
+	// unknown key id
+	unknown := append([]byte{}, encrypted...)
+	unknown[1] = 9
+	_, ok := kr.Decrypt(unknown)
+	assert.False(t, ok)
+
+	// tampered
+	tampered := append([]byte{}, encrypted...)
+	tampered[len(tampered)-1] ^= 1
+	_, ok = kr.Decrypt(tampered)
+	assert.False(t, ok)
+}
+
+func Test_Keyring_Rotation(t *testing.T) {
+	oldKey, newKey := randomKey(), randomKey()
+	old, _ := NewKeyring(1, map[uint8][32]byte{1: oldKey})
+	encrypted, _ := old.Encrypt("leto")
+
+	rotated, _ := NewKeyring(2, map[uint8][32]byte{1: oldKey, 2: newKey})
+	plain, ok := rotated.Decrypt(encrypted)
+	assert.True(t, ok)
+	assert.Equal(t, string(plain), "leto")
+
+	reEncrypted, changed, err := rotated.ReEncrypt(encrypted)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	id, _ := KeyId(reEncrypted)
+	assert.Equal(t, id, 2)
+
+	// the old key is no longer needed
+	current, _ := NewKeyring(2, map[uint8][32]byte{2: newKey})
+	plain, ok = current.Decrypt(reEncrypted)
+	assert.True(t, ok)
+	assert.Equal(t, string(plain), "leto")
+
+	// already using the primary
+	again, changed, err := rotated.ReEncrypt(reEncrypted)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Bytes(t, again, reEncrypted)
+
+	_, _, err = current.ReEncrypt(encrypted)
+	assert.True(t, err == ErrUnknownKey)
+}
+
+func randomKey() [32]byte {
+	var key [32]byte
+	rand.Read(key[:])
+	return key
+}
+
+func Test_Keyring_DecryptTo_Errors(t *testing.T) {
+	kr, _ := NewKeyring(1, map[uint8][32]byte{1: randomKey()})
+	encrypted, _ := kr.Encrypt("hello")
+
+	_, err := kr.DecryptTo(nil, nil)
+	assert.True(t, err == ErrTooShort)
+	_, err = kr.DecryptTo(nil, encrypted[:30])
+	assert.True(t, err == ErrTooShort)
+
+	_, err = kr.DecryptTo(nil, append([]byte{9}, encrypted[1:]...))
+	assert.True(t, err == ErrUnknownVersion)
+
+	_, err = kr.DecryptTo(nil, append([]byte{1, 9}, encrypted[2:]...))
+	assert.True(t, err == ErrUnknownKey)
+
+	tampered := append([]byte{}, encrypted...)
+	tampered[10] ^= 1
+	_, err = kr.DecryptTo(nil, tampered)
+	assert.True(t, err == ErrAuthFailed)
+}
+
+func Test_Keyring_EncryptTo(t *testing.T) {
+	kr, _ := NewKeyring(4, map[uint8][32]byte{4: randomKey()})
+	buf := make([]byte, 0, 64)
+	encrypted, err := kr.EncryptTo(buf, []byte("hello"))
+	assert.Nil(t, err)
+	assert.Equal(t, len(encrypted), 2+40+5)
+	assert.True(t, &encrypted[0] == &buf[:1][0])
+
+	plain, err := kr.DecryptTo(nil, encrypted)
+	assert.Nil(t, err)
+	assert.Equal(t, string(plain), "hello")
+}
+
+func Test_Keyring_ReEncryptLegacy(t *testing.T) {
+	legacy := randomKey()
+	kr, _ := NewKeyring(2, map[uint8][32]byte{1: randomKey(), 2: randomKey()})
+
+	encrypted, _ := Encrypt(legacy, "it's over 9000!!")
+	reEncrypted, changed, err := kr.ReEncryptLegacy(legacy, encrypted)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	id, _ := KeyId(reEncrypted)
+	assert.Equal(t, id, 2)
+	plainText, ok := kr.Decrypt(reEncrypted)
+	assert.True(t, ok)
+	assert.Equal(t, string(plainText), "it's over 9000!!")
+
+	// already migrated
+	again, changed, err := kr.ReEncryptLegacy(legacy, reEncrypted)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+	assert.Bytes(t, again, reEncrypted)
+
+	// migrated, but with an old key
+	old, _ := kr.keyringEncrypt(1, "old")
+	again, changed, err = kr.ReEncryptLegacy(legacy, old)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	plainText, _ = kr.Decrypt(again)
+	assert.Equal(t, string(plainText), "old")
+
+	// wrong legacy key
+	_, _, err = kr.ReEncryptLegacy(randomKey(), encrypted)
+	assert.True(t, err == ErrAuthFailed)
+}
+
+func Test_Keyring_ReEncryptLegacy_HeaderLikeNonce(t *testing.T) {
+	legacy := randomKey()
+	kr, _ := NewKeyring(2, map[uint8][32]byte{2: randomKey()})
+
+	// a legacy value whose random nonce starts with [version][primary]
+	var nonce [nonceLength]byte
+	nonce[0], nonce[1] = keyringVersion, 2
+	encrypted := secretbox.Seal(nonce[:], []byte("leto"), &nonce, &legacy)
+
+	_, err := kr.DecryptTo(nil, encrypted)
+	assert.True(t, err == ErrAuthFailed)
+
+	reEncrypted, changed, err := kr.ReEncryptLegacy(legacy, encrypted)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	plainText, ok := kr.Decrypt(reEncrypted)
+	assert.True(t, ok)
+	assert.Equal(t, string(plainText), "leto")
+}
+
+// encrypts with a specific (non-primary) key
+func (kr *Keyring) keyringEncrypt(id uint8, plainText string) ([]byte, error) {
+	return EncryptTo([]byte{keyringVersion, id}, *kr.keys[id], []byte(plainText))
+}
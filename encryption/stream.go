@@ -0,0 +1,257 @@
+package encryption
+
+/*
+Chunked streaming encryption, for payloads (like sqlite backups) that
+shouldn't be loaded into memory in full.
+
+The stream starts with a header, [version][nonce prefix (16)], followed
+by chunks of up to 64KB of plain text, each sealed with
+XChaCha20-Poly1305. A chunk's nonce is the random prefix followed by a
+7 byte chunk counter and a 1 byte final-chunk flag, so chunks can't be
+reordered, dropped or duplicated, and the stream can't be truncated
+(the last chunk read has to be sealed as the final one).
+
+Every chunk but the last is full, so the reader can tell the last chunk
+from its size. The final chunk can be empty.
+*/
+
+import (
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	streamVersion      = 1
+	streamPrefixLength = 16
+	streamHeaderLength = 1 + streamPrefixLength
+	streamChunkSize    = 64 * 1024
+)
+
+var (
+	ErrStreamClosed = errors.New("encryption: stream closed")
+)
+
+type streamNonce struct {
+	nonce   [chacha20poly1305.NonceSizeX]byte
+	counter uint64
+}
+
+// Returns the nonce for the next chunk and advances the counter
+func (n *streamNonce) next(final bool) []byte {
+	// 7 bytes: 72 quadrillion chunks of 64KB should be enough
+	var c [8]byte
+	binary.BigEndian.PutUint64(c[:], n.counter)
+	copy(n.nonce[streamPrefixLength:], c[1:])
+	if final {
+		n.nonce[23] = 1
+	} else {
+		n.nonce[23] = 0
+	}
+	n.counter++
+	return n.nonce[:]
+}
+
+type StreamWriter struct {
+	w         io.Writer
+	aead      cipher.AEAD
+	nonce     streamNonce
+	buf       []byte
+	chunkSize int
+	err       error
+}
+
+// Writes the header to w. Close must be called to write the final chunk;
+// it doesn't close w.
+func NewStreamWriter(key [32]byte, w io.Writer) (*StreamWriter, error) {
+	return newStreamWriter(key, w, streamChunkSize)
+}
+
+func newStreamWriter(key [32]byte, w io.Writer, chunkSize int) (*StreamWriter, error) {
+	sw := &StreamWriter{
+		w:         w,
+		aead:      newXChaCha(key[:]),
+		chunkSize: chunkSize,
+		buf:       make([]byte, 0, chunkSize+chacha20poly1305.Overhead),
+	}
+
+	var header [streamHeaderLength]byte
+	header[0] = streamVersion
+	if _, err := io.ReadFull(rand.Reader, header[1:]); err != nil {
+		return nil, err
+	}
+	copy(sw.nonce.nonce[:], header[1:])
+
+	if _, err := w.Write(header[:]); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+func (sw *StreamWriter) Write(p []byte) (int, error) {
+	if sw.err != nil {
+		return 0, sw.err
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// only flush a full chunk once we know more data follows, since
+		// the last chunk has to be flagged as final
+		if len(sw.buf) == sw.chunkSize {
+			if err := sw.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := sw.chunkSize - len(sw.buf)
+		if n > len(p) {
+			n = len(p)
+		}
+		sw.buf = append(sw.buf, p[:n]...)
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Writes the final chunk. The writer can't be used afterwards.
+func (sw *StreamWriter) Close() error {
+	if sw.err != nil {
+		if sw.err == ErrStreamClosed {
+			return nil
+		}
+		return sw.err
+	}
+	if err := sw.flush(true); err != nil {
+		return err
+	}
+	sw.err = ErrStreamClosed
+	return nil
+}
+
+func (sw *StreamWriter) flush(final bool) error {
+	sealed := sw.aead.Seal(sw.buf[:0], sw.nonce.next(final), sw.buf, nil)
+	if _, err := sw.w.Write(sealed); err != nil {
+		sw.err = err
+		return err
+	}
+	sw.buf = sw.buf[:0]
+	return nil
+}
+
+type StreamReader struct {
+	r         io.Reader
+	aead      cipher.AEAD
+	nonce     streamNonce
+	chunkSize int
+	header    bool
+
+	// sealed chunk buffer, with room for 1 byte of the next chunk
+	in []byte
+
+	// the first byte of the next chunk, read along with the current one
+	carry    byte
+	hasCarry bool
+
+	// decrypted, unread data from the current chunk
+	out []byte
+
+	final bool
+	err   error
+}
+
+// The header is read on the first call to Read. Read returns
+// ErrUnknownVersion, ErrTooShort or ErrAuthFailed when the stream is
+// invalid; decrypted data is only returned once its chunk has been
+// authenticated.
+func NewStreamReader(key [32]byte, r io.Reader) *StreamReader {
+	return newStreamReader(key, r, streamChunkSize)
+}
+
+func newStreamReader(key [32]byte, r io.Reader, chunkSize int) *StreamReader {
+	return &StreamReader{
+		r:         r,
+		aead:      newXChaCha(key[:]),
+		chunkSize: chunkSize,
+		in:        make([]byte, chunkSize+chacha20poly1305.Overhead+1),
+	}
+}
+
+func (sr *StreamReader) Read(p []byte) (int, error) {
+	for len(sr.out) == 0 {
+		if sr.err != nil {
+			return 0, sr.err
+		}
+		if sr.final {
+			return 0, io.EOF
+		}
+		if err := sr.readChunk(); err != nil {
+			sr.err = err
+			return 0, err
+		}
+	}
+
+	n := copy(p, sr.out)
+	sr.out = sr.out[n:]
+	return n, nil
+}
+
+func (sr *StreamReader) readChunk() error {
+	if !sr.header {
+		var header [streamHeaderLength]byte
+		if _, err := io.ReadFull(sr.r, header[:]); err != nil {
+			return streamReadError(err)
+		}
+		if header[0] != streamVersion {
+			return ErrUnknownVersion
+		}
+		copy(sr.nonce.nonce[:], header[1:])
+		sr.header = true
+	}
+
+	// Read a full sealed chunk plus 1 byte. If we get that extra byte,
+	// this isn't the final chunk, and the byte belongs to the next one.
+	in := sr.in
+	carried := 0
+	if sr.hasCarry {
+		in[0] = sr.carry
+		carried = 1
+	}
+
+	n, err := io.ReadFull(sr.r, in[carried:])
+	n += carried
+
+	switch err {
+	case nil:
+		sr.carry = in[n-1]
+		sr.hasCarry = true
+		n--
+	case io.ErrUnexpectedEOF, io.EOF:
+		sr.final = true
+	default:
+		return err
+	}
+
+	sealed := in[:n]
+	if len(sealed) < chacha20poly1305.Overhead {
+		return ErrTooShort
+	}
+
+	// decrypt in place, the plain text is shorter than the sealed chunk
+	out, err := sr.aead.Open(sealed[:0], sr.nonce.next(sr.final), sealed, nil)
+	if err != nil {
+		return ErrAuthFailed
+	}
+	sr.out = out
+	return nil
+}
+
+func streamReadError(err error) error {
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return ErrTooShort
+	}
+	return err
+}
@@ -0,0 +1,155 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"src.sqlkite.com/tests/assert"
+)
+
+func Test_Stream_RoundTrip(t *testing.T) {
+	key := randomKey()
+	// sizes around the chunk boundaries
+	for _, size := range []int{0, 1, 15, 16, 17, 31, 32, 33, 100} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		encrypted := streamEncrypt(t, key, 16, plain)
+		chunks := (size + 15) / 16
+		if chunks == 0 {
+			chunks = 1
+		}
+		assert.Equal(t, len(encrypted), 17+size+chunks*16)
+
+		decrypted, err := io.ReadAll(newStreamReader(key, bytes.NewReader(encrypted), 16))
+		assert.Nil(t, err)
+		assert.Bytes(t, decrypted, plain)
+	}
+}
+
+func Test_Stream_DefaultChunkSize(t *testing.T) {
+	key := randomKey()
+	plain := make([]byte, 200_000)
+	rand.Read(plain)
+
+	var buf bytes.Buffer
+	w, err := NewStreamWriter(key, &buf)
+	assert.Nil(t, err)
+	// odd sized writes
+	for i := 0; i < len(plain); i += 7001 {
+		end := i + 7001
+		if end > len(plain) {
+			end = len(plain)
+		}
+		n, err := w.Write(plain[i:end])
+		assert.Nil(t, err)
+		assert.Equal(t, n, end-i)
+	}
+	assert.Nil(t, w.Close())
+	assert.Nil(t, w.Close())
+	_, err = w.Write([]byte{1})
+	assert.True(t, err == ErrStreamClosed)
+
+	decrypted, err := io.ReadAll(NewStreamReader(key, &buf))
+	assert.Nil(t, err)
+	assert.Bytes(t, decrypted, plain)
+}
+
+func Test_Stream_Tampering(t *testing.T) {
+	key := randomKey()
+	plain := make([]byte, 40)
+	rand.Read(plain)
+	encrypted := streamEncrypt(t, key, 16, plain)
+	// header (17) + 3 sealed chunks of 16 bytes of data + 16 byte tag
+	// (the last has 8 bytes of data)
+	chunk := func(i int) []byte {
+		start := 17 + i*32
+		end := start + 32
+		if end > len(encrypted) {
+			end = len(encrypted)
+		}
+		return encrypted[start:end]
+	}
+
+	assertStreamError(t, key, nil, ErrTooShort)
+	assertStreamError(t, key, encrypted[:10], ErrTooShort)
+	assertStreamError(t, key, encrypted[:17], ErrTooShort)
+	assertStreamError(t, key, append([]byte{2}, encrypted[1:]...), ErrUnknownVersion)
+	assertStreamError(t, randomKey(), encrypted, ErrAuthFailed)
+
+	// truncated at a chunk boundary
+	assertStreamError(t, key, encrypted[:17+32], ErrAuthFailed)
+	assertStreamError(t, key, encrypted[:17+64], ErrAuthFailed)
+
+	// truncated mid chunk
+	assertStreamError(t, key, encrypted[:len(encrypted)-1], ErrAuthFailed)
+
+	// reordered
+	reordered := join(encrypted[:17], chunk(1), chunk(0), chunk(2))
+	assertStreamError(t, key, reordered, ErrAuthFailed)
+
+	// dropped
+	assertStreamError(t, key, join(encrypted[:17], chunk(0), chunk(2)), ErrAuthFailed)
+
+	// trailing data
+	assertStreamError(t, key, join(encrypted, []byte{1}), ErrAuthFailed)
+
+	// flipped bit
+	for _, i := range []int{1, 17, 40, len(encrypted) - 1} {
+		tampered := join(encrypted)
+		tampered[i] ^= 1
+		assertStreamError(t, key, tampered, ErrAuthFailed)
+	}
+}
+
+func Test_Stream_ReadsAuthenticatedChunks(t *testing.T) {
+	key := randomKey()
+	plain := make([]byte, 40)
+	rand.Read(plain)
+	encrypted := streamEncrypt(t, key, 16, plain)
+	encrypted[len(encrypted)-1] ^= 1
+
+	// the first chunks are returned, the error surfaces on the last
+	decrypted, err := io.ReadAll(newStreamReader(key, bytes.NewReader(encrypted), 16))
+	assert.True(t, err == ErrAuthFailed)
+	assert.Bytes(t, decrypted, plain[:32])
+}
+
+func Test_Stream_WriterError(t *testing.T) {
+	_, err := NewStreamWriter(randomKey(), errWriter{})
+	assert.Equal(t, err.Error(), "write failed")
+}
+
+func streamEncrypt(t *testing.T, key [32]byte, chunkSize int, plain []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := newStreamWriter(key, &buf, chunkSize)
+	assert.Nil(t, err)
+	_, err = w.Write(plain)
+	assert.Nil(t, err)
+	assert.Nil(t, w.Close())
+	return buf.Bytes()
+}
+
+func assertStreamError(t *testing.T, key [32]byte, encrypted []byte, expected error) {
+	t.Helper()
+	_, err := io.ReadAll(newStreamReader(key, bytes.NewReader(encrypted), 16))
+	assert.True(t, err == expected)
+}
+
+func join(parts ...[]byte) []byte {
+	var joined []byte
+	for _, part := range parts {
+		joined = append(joined, part...)
+	}
+	return joined
+}
+
+type errWriter struct{}
+
+func (errWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
@@ -14,6 +14,7 @@ require (
 	github.com/valyala/fasthttp v1.43.0
 	golang.org/x/crypto v0.3.0
 	golang.org/x/sync v0.1.0
+	golang.org/x/text v0.4.0
 	src.sqlkite.com/sqlite v0.0.0-20221128090856-d9f1b8c6d25c
 	src.sqlkite.com/tests v0.0.0-20221201041742-c95aa6465a01
 )
@@ -27,5 +28,4 @@ require (
 	github.com/valyala/bytebufferpool v1.0.0 // indirect
 	go.uber.org/atomic v1.10.0 // indirect
 	golang.org/x/sys v0.2.0 // indirect
-	golang.org/x/text v0.4.0 // indirect
 )
@@ -0,0 +1,270 @@
+package http
+
+/*
+By default, the request line logged by Handler and NoEnvHandler has
+the route, duration and whatever the response adds (status, code,
+res, ...). ConfigureAccessLog adds more request/response details.
+
+The configuration is turned into a list of small functions once, at
+startup, so that for each request we only do the work for the fields
+that were asked for. ConfigureAccessLog is meant to be called before
+the server starts, but the configuration is swapped atomically, so
+calling it while requests are being handled is safe.
+*/
+
+import (
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/log"
+)
+
+var (
+	// *accessLogger, read by every request
+	accessLog atomic.Value
+
+	defaultRedact = []string{"password", "secret", "token", "key", "api_key"}
+)
+
+type AccessLogConfig struct {
+	Method    bool `json:"method"`
+	Path      bool `json:"path"`
+	Query     bool `json:"query"`
+	RemoteIP  bool `json:"remote_ip"`
+	UserAgent bool `json:"user_agent"`
+	ReqSize   bool `json:"req_size"`
+
+	// Query string parameters whose values are replaced before being
+	// logged. Matching is case-insensitive and on whole words of the
+	// name, where words are separated by non-alphanumeric characters
+	// or a change to uppercase. So "token" also redacts access_token,
+	// Refresh-Token and idToken, but "key" doesn't redact monkey or
+	// keyword. Defaults to common credential names.
+	Redact []string `json:"redact"`
+
+	// IPs or CIDRs of proxies which we trust to set X-Forwarded-For.
+	TrustedProxies []string `json:"trusted_proxies"`
+
+	// Response headers to log. Each is logged with a key of h_ followed
+	// by the lowercase header name, with dashes replaced by underscores
+	// (e.g. Content-Type => h_content_type)
+	ResHeaders []string `json:"res_headers"`
+}
+
+type accessLogField func(conn *fasthttp.RequestCtx, logger log.Logger)
+
+type accessLogger struct {
+	fields []accessLogField
+}
+
+func init() {
+	accessLog.Store(&accessLogger{})
+}
+
+func enhanceAccessLog(conn *fasthttp.RequestCtx, logger log.Logger) log.Logger {
+	return accessLog.Load().(*accessLogger).enhance(conn, logger)
+}
+
+func (a accessLogger) enhance(conn *fasthttp.RequestCtx, logger log.Logger) log.Logger {
+	for _, field := range a.fields {
+		field(conn, logger)
+	}
+	return logger
+}
+
+func ConfigureAccessLog(config AccessLogConfig) error {
+	var fields []accessLogField
+
+	if config.Method {
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			logger.String("method", utils.B2S(conn.Method()))
+		})
+	}
+
+	if config.Path {
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			logger.String("path", utils.B2S(conn.Path()))
+		})
+	}
+
+	if config.Query {
+		redact := config.Redact
+		if redact == nil {
+			redact = defaultRedact
+		}
+		lowered := make([]string, len(redact))
+		for i, r := range redact {
+			lowered[i] = strings.ToLower(r)
+		}
+		redact = lowered
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			if query := redactedQuery(conn.QueryArgs(), redact); query != "" {
+				logger.String("query", query)
+			}
+		})
+	}
+
+	if config.RemoteIP {
+		proxies, err := parseTrustedProxies(config.TrustedProxies)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			logger.String("ip", clientIP(conn, proxies))
+		})
+	}
+
+	if config.UserAgent {
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			logger.String("agent", utils.B2S(conn.UserAgent()))
+		})
+	}
+
+	if config.ReqSize {
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			logger.Int("req", len(conn.Request.Body()))
+		})
+	}
+
+	for _, name := range config.ResHeaders {
+		name := name
+		key := "h_" + strings.ReplaceAll(strings.ToLower(name), "-", "_")
+		fields = append(fields, func(conn *fasthttp.RequestCtx, logger log.Logger) {
+			if value := conn.Response.Header.Peek(name); value != nil {
+				logger.String(key, utils.B2S(value))
+			}
+		})
+	}
+
+	accessLog.Store(&accessLogger{fields: fields})
+	log.Info("access_log_config").
+		Int("fields", len(fields)).
+		Log()
+	return nil
+}
+
+func redactedQuery(args *fasthttp.Args, redact []string) string {
+	if args.Len() == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	args.VisitAll(func(key []byte, value []byte) {
+		if sb.Len() > 0 {
+			sb.WriteByte('&')
+		}
+		sb.Write(key)
+		sb.WriteByte('=')
+
+		k := utils.B2S(key)
+		for _, r := range redact {
+			if containsWord(k, r) {
+				sb.WriteString("REDACTED")
+				return
+			}
+		}
+		sb.Write(value)
+	})
+	return sb.String()
+}
+
+// Whether word (lowercase) appears in s, case-insensitively, as one or
+// more whole words (see AccessLogConfig.Redact)
+func containsWord(s string, word string) bool {
+	lower := strings.ToLower(s)
+	for offset := 0; ; {
+		i := strings.Index(lower[offset:], word)
+		if i == -1 {
+			return false
+		}
+		start := offset + i
+		end := start + len(word)
+		if wordBoundary(s, start) && wordBoundary(s, end) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+// Whether a word can start or end at position i of s
+func wordBoundary(s string, i int) bool {
+	if i == 0 || i == len(s) {
+		return true
+	}
+	prev, next := s[i-1], s[i]
+	if !isAlphaNumeric(prev) || !isAlphaNumeric(next) {
+		return true
+	}
+	// camelCase
+	return isUpper(next) && !isUpper(prev)
+}
+
+func isAlphaNumeric(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isUpper(c byte) bool {
+	return c >= 'A' && c <= 'Z'
+}
+
+func parseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, len(proxies))
+	for i, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, n, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, log.Err(utils.ERR_HTTP_TRUSTED_PROXY, err).String("proxy", proxies[i])
+		}
+		nets[i] = n
+	}
+	return nets, nil
+}
+
+// The client's IP. When the request comes from a trusted proxy, we walk
+// X-Forwarded-For from right to left (the right-most entry being the one
+// added by the proxy closest to us) and take the first untrusted address.
+func clientIP(conn *fasthttp.RequestCtx, proxies []*net.IPNet) string {
+	ip := conn.RemoteIP()
+	if !trustedProxy(ip, proxies) {
+		return ip.String()
+	}
+
+	forwarded := utils.B2S(conn.Request.Header.Peek("X-Forwarded-For"))
+	for forwarded != "" {
+		var entry string
+		if i := strings.LastIndexByte(forwarded, ','); i == -1 {
+			entry, forwarded = forwarded, ""
+		} else {
+			entry, forwarded = forwarded[i+1:], forwarded[:i]
+		}
+
+		forwardedIP := net.ParseIP(strings.TrimSpace(entry))
+		if forwardedIP == nil {
+			// can't trust anything further left
+			break
+		}
+		ip = forwardedIP
+		if !trustedProxy(ip, proxies) {
+			break
+		}
+	}
+	return ip.String()
+}
+
+func trustedProxy(ip net.IP, proxies []*net.IPNet) bool {
+	for _, proxy := range proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
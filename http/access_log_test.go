@@ -0,0 +1,165 @@
+package http
+
+import (
+	"net"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/log"
+)
+
+func Test_AccessLog_Default(t *testing.T) {
+	reqLog := accessLogRequest(t, AccessLogConfig{}, accessLogConn("9.9.9.9"))
+	assert.Equal(t, reqLog["route"], "test")
+	_, exists := reqLog["method"]
+	assert.False(t, exists)
+	_, exists = reqLog["ip"]
+	assert.False(t, exists)
+}
+
+func Test_AccessLog_RequestFields(t *testing.T) {
+	conn := accessLogConn("9.9.9.9")
+	conn.Request.Header.SetMethod("POST")
+	conn.Request.SetRequestURI("/v1/users?page=2&token=abc&name=leto")
+	conn.Request.Header.SetUserAgent("curl/7.1")
+	conn.Request.SetBodyString(`{"over":9000}`)
+
+	reqLog := accessLogRequest(t, AccessLogConfig{
+		Method:    true,
+		Path:      true,
+		Query:     true,
+		RemoteIP:  true,
+		UserAgent: true,
+		ReqSize:   true,
+	}, conn)
+
+	assert.Equal(t, reqLog["method"], "POST")
+	assert.Equal(t, reqLog["path"], "/v1/users")
+	assert.Equal(t, reqLog["query"], `"page=2&token=REDACTED&name=leto"`)
+	assert.Equal(t, reqLog["ip"], "9.9.9.9")
+	assert.Equal(t, reqLog["agent"], "curl/7.1")
+	assert.Equal(t, reqLog["req"], "13")
+	assert.Equal(t, reqLog["status"], "200")
+}
+
+func Test_AccessLog_CustomRedact(t *testing.T) {
+	conn := accessLogConn("9.9.9.9")
+	conn.Request.SetRequestURI("/?token=abc&ssn=123")
+
+	reqLog := accessLogRequest(t, AccessLogConfig{Query: true, Redact: []string{"ssn"}}, conn)
+	assert.Equal(t, reqLog["query"], `"token=abc&ssn=REDACTED"`)
+
+	// no query, no field
+	reqLog = accessLogRequest(t, AccessLogConfig{Query: true}, accessLogConn("9.9.9.9"))
+	_, exists := reqLog["query"]
+	assert.False(t, exists)
+}
+
+func Test_AccessLog_RedactMatching(t *testing.T) {
+	conn := accessLogConn("9.9.9.9")
+	conn.Request.SetRequestURI("/?access_token=abc&Password=hunter2&refresh-token=r&X-API-KEY=k&idToken=i&user[api_key]=u&page=1")
+
+	reqLog := accessLogRequest(t, AccessLogConfig{Query: true}, conn)
+	assert.Equal(t, reqLog["query"], `"access_token=REDACTED&Password=REDACTED&refresh-token=REDACTED&X-API-KEY=REDACTED&idToken=REDACTED&user[api_key]=REDACTED&page=1"`)
+
+	// only whole words match
+	conn = accessLogConn("9.9.9.9")
+	conn.Request.SetRequestURI("/?monkey=1&keyword=2&passwords=3&tokenize=4&keys=5")
+	reqLog = accessLogRequest(t, AccessLogConfig{Query: true}, conn)
+	assert.Equal(t, reqLog["query"], `"monkey=1&keyword=2&passwords=3&tokenize=4&keys=5"`)
+
+	conn = accessLogConn("9.9.9.9")
+	conn.Request.SetRequestURI("/?user_SSN=123&ssn2=4&lessn=5")
+	reqLog = accessLogRequest(t, AccessLogConfig{Query: true, Redact: []string{"SSN"}}, conn)
+	assert.Equal(t, reqLog["query"], `"user_SSN=REDACTED&ssn2=4&lessn=5"`)
+}
+
+func Test_AccessLog_ConcurrentConfigure(t *testing.T) {
+	defer ConfigureAccessLog(AccessLogConfig{})
+
+	// meant to be run with -race
+	tests.CaptureLog(func() {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			for i := 0; i < 100; i++ {
+				ConfigureAccessLog(AccessLogConfig{Method: i%2 == 0})
+			}
+		}()
+
+		for i := 0; i < 100; i++ {
+			NoEnvHandler("test", func(conn *fasthttp.RequestCtx) (Response, error) {
+				return OkBytes([]byte(`{}`)), nil
+			})(accessLogConn("9.9.9.9"))
+		}
+		<-done
+	})
+}
+
+func Test_AccessLog_ResHeaders(t *testing.T) {
+	reqLog := accessLogRequest(t, AccessLogConfig{
+		ResHeaders: []string{"Content-Type", "X-Power", "X-Missing"},
+	}, accessLogConn("9.9.9.9"))
+
+	assert.Equal(t, reqLog["h_content_type"], "application/json")
+	assert.Equal(t, reqLog["h_x_power"], "9001")
+	_, exists := reqLog["h_x_missing"]
+	assert.False(t, exists)
+}
+
+func Test_AccessLog_InvalidTrustedProxy(t *testing.T) {
+	err := ConfigureAccessLog(AccessLogConfig{RemoteIP: true, TrustedProxies: []string{"nope"}})
+	assert.Equal(t, err.(*log.StructuredError).Code, 3008)
+}
+
+func Test_AccessLog_ForwardedFor(t *testing.T) {
+	config := AccessLogConfig{
+		RemoteIP:       true,
+		TrustedProxies: []string{"10.0.0.0/8", "192.168.1.1", "::1"},
+	}
+
+	// untrusted remote, header ignored
+	conn := accessLogConn("9.9.9.9")
+	conn.Request.Header.Set("X-Forwarded-For", "1.1.1.1")
+	assert.Equal(t, accessLogRequest(t, config, conn)["ip"], "9.9.9.9")
+
+	// trusted remote, no header
+	conn = accessLogConn("10.1.2.3")
+	assert.Equal(t, accessLogRequest(t, config, conn)["ip"], "10.1.2.3")
+
+	// trusted remote, first untrusted from the right
+	conn = accessLogConn("10.1.2.3")
+	conn.Request.Header.Set("X-Forwarded-For", "6.6.6.6, 2.2.2.2, 192.168.1.1")
+	assert.Equal(t, accessLogRequest(t, config, conn)["ip"], "2.2.2.2")
+
+	// everything trusted
+	conn = accessLogConn("10.1.2.3")
+	conn.Request.Header.Set("X-Forwarded-For", "10.0.0.1,192.168.1.1")
+	assert.Equal(t, accessLogRequest(t, config, conn)["ip"], "10.0.0.1")
+
+	// garbage stops the walk
+	conn = accessLogConn("10.1.2.3")
+	conn.Request.Header.Set("X-Forwarded-For", "2.2.2.2, nope, 10.0.0.1")
+	assert.Equal(t, accessLogRequest(t, config, conn)["ip"], "10.0.0.1")
+}
+
+func accessLogRequest(t *testing.T, config AccessLogConfig, conn *fasthttp.RequestCtx) map[string]string {
+	t.Helper()
+	defer ConfigureAccessLog(AccessLogConfig{})
+
+	logged := tests.CaptureLog(func() {
+		assert.Nil(t, ConfigureAccessLog(config))
+		NoEnvHandler("test", func(conn *fasthttp.RequestCtx) (Response, error) {
+			return OkBytes([]byte(`{}`)).Header("X-Power", "9001"), nil
+		})(conn)
+	})
+	return log.KvParseAll(logged)[1]
+}
+
+func accessLogConn(remoteIP string) *fasthttp.RequestCtx {
+	conn := &fasthttp.RequestCtx{}
+	conn.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.ParseIP(remoteIP)}, nil)
+	return conn
+}
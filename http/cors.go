@@ -0,0 +1,205 @@
+package http
+
+/*
+CORS support. Everything that doesn't depend on the request's origin
+is computed once, when the Cors is created. The preflight response is
+a StaticResponse (with its headers already attached), and the
+headers added to normal responses are prepared strings.
+
+Wrap a handler (typically the result of Handler or NoEnvHandler) so
+that its responses are decorated and so that preflight requests to
+the route are answered without calling it:
+
+	cors, err := http.NewCors(http.CorsConfig{Origins: []string{"https://*.sqlkite.com"}})
+	router.POST("/v1/users", cors.Wrap(http.Handler("users_create", loadEnv, createUser)))
+
+Preflight can also be registered directly, e.g. as a router's global
+OPTIONS handler.
+
+Allowed origins can be exact ("https://app.sqlkite.com"), contain a
+single wildcard ("https://*.sqlkite.com") or be "*" to allow any origin.
+"*" can't be combined with Credentials: that would let any site make
+credentialed requests and read the responses. When credentials are
+allowed, the request's origin is echoed back, as browsers require.
+
+Since the headers depend on the request's origin, every response that
+goes through a Cors (including those to disallowed origins) gets a
+"Vary: Origin".
+*/
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/log"
+)
+
+type CorsConfig struct {
+	Origins     []string `json:"origins"`
+	Methods     []string `json:"methods"`
+	Headers     []string `json:"headers"`
+	Expose      []string `json:"expose"`
+	Credentials bool     `json:"credentials"`
+
+	// in seconds
+	MaxAge uint32 `json:"max_age"`
+}
+
+type Cors struct {
+	anyOrigin   bool
+	credentials bool
+	expose      string
+	exact       map[string]struct{}
+	wildcards   []corsWildcard
+	preflight   StaticResponse
+}
+
+type corsWildcard struct {
+	prefix string
+	suffix string
+}
+
+func NewCors(config CorsConfig) (*Cors, error) {
+	methods := config.Methods
+	if len(methods) == 0 {
+		methods = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
+	}
+
+	headers := config.Headers
+	if len(headers) == 0 {
+		headers = []string{"Content-Type", "Authorization"}
+	}
+
+	maxAge := config.MaxAge
+	if maxAge == 0 {
+		maxAge = 600
+	}
+
+	c := &Cors{
+		credentials: config.Credentials,
+		expose:      strings.Join(config.Expose, ", "),
+		exact:       make(map[string]struct{}, len(config.Origins)),
+	}
+
+	for _, origin := range config.Origins {
+		if origin == "*" {
+			if config.Credentials {
+				return nil, log.Errf(utils.ERR_HTTP_CORS, "cors origin * can't be used with credentials")
+			}
+			c.anyOrigin = true
+		} else if i := strings.IndexByte(origin, '*'); i != -1 {
+			c.wildcards = append(c.wildcards, corsWildcard{
+				prefix: origin[:i],
+				suffix: origin[i+1:],
+			})
+		} else {
+			c.exact[origin] = struct{}{}
+		}
+	}
+
+	preflight := StaticResponse{
+		status: 204,
+		logData: log.NewField().
+			Int("status", 204).
+			Int("res", 0).
+			Finalize(),
+	}.
+		Header("Access-Control-Allow-Methods", strings.Join(methods, ", ")).
+		Header("Access-Control-Allow-Headers", strings.Join(headers, ", ")).
+		Header("Access-Control-Max-Age", strconv.Itoa(int(maxAge)))
+
+	if c.credentials {
+		preflight = preflight.Header("Access-Control-Allow-Credentials", "true")
+	}
+	c.preflight = preflight
+
+	return c, nil
+}
+
+func (c *Cors) Wrap(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+	return func(conn *fasthttp.RequestCtx) {
+		if isPreflight(conn) {
+			c.Preflight(conn)
+			return
+		}
+
+		next(conn)
+		c.decorate(conn)
+	}
+}
+
+// Answers a preflight (OPTIONS) request. Preflights from origins which
+// aren't allowed get an empty 204, without any CORS headers, which the
+// browser will treat as a rejection.
+func (c *Cors) Preflight(conn *fasthttp.RequestCtx) {
+	var res StaticResponse
+	origin := utils.B2S(conn.Request.Header.Peek("Origin"))
+	conn.Response.Header.Add("Vary", "Origin")
+	if c.allowed(origin) {
+		res = c.preflight
+		c.writeOrigin(conn, origin)
+	} else {
+		res = NoContent()
+	}
+
+	res.Write(conn)
+	res.EnhanceLog(log.Info("req")).
+		String("route", "cors_preflight").
+		Log()
+}
+
+func (c *Cors) decorate(conn *fasthttp.RequestCtx) {
+	origin := utils.B2S(conn.Request.Header.Peek("Origin"))
+	conn.Response.Header.Add("Vary", "Origin")
+	if !c.allowed(origin) {
+		return
+	}
+
+	c.writeOrigin(conn, origin)
+	h := &conn.Response.Header
+	if c.credentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+	if expose := c.expose; expose != "" {
+		h.Set("Access-Control-Expose-Headers", expose)
+	}
+}
+
+func (c *Cors) writeOrigin(conn *fasthttp.RequestCtx, origin string) {
+	h := &conn.Response.Header
+	if c.anyOrigin {
+		h.Set("Access-Control-Allow-Origin", "*")
+		return
+	}
+	h.Set("Access-Control-Allow-Origin", origin)
+}
+
+func (c *Cors) allowed(origin string) bool {
+	if origin == "" {
+		return false
+	}
+
+	if c.anyOrigin {
+		return true
+	}
+
+	if _, ok := c.exact[origin]; ok {
+		return true
+	}
+
+	for _, w := range c.wildcards {
+		// the wildcard must match at least one character
+		if len(origin) > len(w.prefix)+len(w.suffix) &&
+			strings.HasPrefix(origin, w.prefix) &&
+			strings.HasSuffix(origin, w.suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isPreflight(conn *fasthttp.RequestCtx) bool {
+	return conn.IsOptions() && len(conn.Request.Header.Peek("Access-Control-Request-Method")) > 0
+}
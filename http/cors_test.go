@@ -0,0 +1,158 @@
+package http
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/log"
+)
+
+func Test_Cors_Preflight(t *testing.T) {
+	cors := mustCors(CorsConfig{
+		Origins: []string{"https://app.sqlkite.com"},
+		Methods: []string{"GET", "POST"},
+		Headers: []string{"Content-Type", "X-Api-Key"},
+		MaxAge:  60,
+	})
+
+	called := false
+	handler := cors.Wrap(func(conn *fasthttp.RequestCtx) {
+		called = true
+	})
+
+	conn := corsConn("OPTIONS", "https://app.sqlkite.com", true)
+	logged := tests.CaptureLog(func() {
+		handler(conn)
+	})
+	assert.False(t, called)
+
+	res := &conn.Response
+	assert.Equal(t, res.StatusCode(), 204)
+	assert.Equal(t, len(res.Body()), 0)
+	assert.Equal(t, string(res.Header.Peek("Access-Control-Allow-Origin")), "https://app.sqlkite.com")
+	assert.Equal(t, string(res.Header.Peek("Access-Control-Allow-Methods")), "GET, POST")
+	assert.Equal(t, string(res.Header.Peek("Access-Control-Allow-Headers")), "Content-Type, X-Api-Key")
+	assert.Equal(t, string(res.Header.Peek("Access-Control-Max-Age")), "60")
+	assert.Equal(t, string(res.Header.Peek("Vary")), "Origin")
+	assert.Equal(t, len(res.Header.Peek("Access-Control-Allow-Credentials")), 0)
+
+	reqLog := log.KvParse(logged)
+	assert.Equal(t, reqLog["status"], "204")
+	assert.Equal(t, reqLog["route"], "cors_preflight")
+}
+
+func Test_Cors_Preflight_Defaults(t *testing.T) {
+	cors := mustCors(CorsConfig{Origins: []string{"*"}})
+	conn := corsConn("OPTIONS", "https://other.com", true)
+	tests.CaptureLog(func() {
+		cors.Preflight(conn)
+	})
+
+	h := &conn.Response.Header
+	assert.Equal(t, string(h.Peek("Access-Control-Allow-Origin")), "*")
+	assert.Equal(t, string(h.Peek("Access-Control-Allow-Methods")), "GET, POST, PUT, PATCH, DELETE")
+	assert.Equal(t, string(h.Peek("Access-Control-Allow-Headers")), "Content-Type, Authorization")
+	assert.Equal(t, string(h.Peek("Access-Control-Max-Age")), "600")
+}
+
+func Test_Cors_Preflight_DisallowedOrigin(t *testing.T) {
+	cors := mustCors(CorsConfig{Origins: []string{"https://app.sqlkite.com"}})
+	conn := corsConn("OPTIONS", "https://evil.com", true)
+	tests.CaptureLog(func() {
+		cors.Preflight(conn)
+	})
+
+	h := &conn.Response.Header
+	assert.Equal(t, conn.Response.StatusCode(), 204)
+	assert.Equal(t, len(h.Peek("Access-Control-Allow-Origin")), 0)
+	assert.Equal(t, len(h.Peek("Access-Control-Allow-Methods")), 0)
+	assert.Equal(t, string(h.Peek("Vary")), "Origin")
+}
+
+func Test_Cors_Decorates(t *testing.T) {
+	cors := mustCors(CorsConfig{
+		Origins:     []string{"https://*.sqlkite.com"},
+		Expose:      []string{"RateLimit-Remaining", "Error-Id"},
+		Credentials: true,
+	})
+
+	handler := cors.Wrap(NoEnvHandler("test", func(conn *fasthttp.RequestCtx) (Response, error) {
+		return Ok(map[string]any{"ok": true}), nil
+	}))
+
+	// an OPTIONS without Access-Control-Request-Method isn't a preflight
+	for _, method := range []string{"GET", "OPTIONS"} {
+		conn := corsConn(method, "https://app.sqlkite.com", false)
+		tests.CaptureLog(func() {
+			handler(conn)
+		})
+
+		h := &conn.Response.Header
+		assert.Equal(t, conn.Response.StatusCode(), 200)
+		assert.Equal(t, string(conn.Response.Body()), `{"ok":true}`)
+		assert.Equal(t, string(h.Peek("Access-Control-Allow-Origin")), "https://app.sqlkite.com")
+		assert.Equal(t, string(h.Peek("Access-Control-Allow-Credentials")), "true")
+		assert.Equal(t, string(h.Peek("Access-Control-Expose-Headers")), "RateLimit-Remaining, Error-Id")
+		assert.Equal(t, string(h.Peek("Vary")), "Origin")
+	}
+}
+
+func Test_Cors_AnyOriginWithCredentials(t *testing.T) {
+	cors, err := NewCors(CorsConfig{Origins: []string{"https://app.sqlkite.com", "*"}, Credentials: true})
+	assert.Nil(t, cors)
+	assert.Equal(t, err.(*log.StructuredError).Code, 3011)
+}
+
+func Test_Cors_Decorates_AnyOrigin(t *testing.T) {
+	cors := mustCors(CorsConfig{Origins: []string{"*"}})
+	for _, origin := range []string{"https://other.com", "null"} {
+		conn := corsConn("GET", origin, false)
+		cors.Wrap(func(conn *fasthttp.RequestCtx) {})(conn)
+
+		h := &conn.Response.Header
+		assert.Equal(t, string(h.Peek("Access-Control-Allow-Origin")), "*")
+		assert.Equal(t, len(h.Peek("Access-Control-Allow-Credentials")), 0)
+		assert.Equal(t, string(h.Peek("Vary")), "Origin")
+	}
+}
+
+func Test_Cors_Decorates_DisallowedOrigin(t *testing.T) {
+	cors := mustCors(CorsConfig{Origins: []string{"https://*.sqlkite.com"}})
+
+	for _, origin := range []string{"", "https://evil.com", "https://.sqlkite.com", "http://app.sqlkite.com"} {
+		conn := corsConn("GET", origin, false)
+		cors.Wrap(func(conn *fasthttp.RequestCtx) {
+			conn.SetStatusCode(200)
+		})(conn)
+
+		h := &conn.Response.Header
+		assert.Equal(t, conn.Response.StatusCode(), 200)
+		assert.Equal(t, len(h.Peek("Access-Control-Allow-Origin")), 0)
+
+		// the response still depends on the origin
+		assert.Equal(t, string(h.Peek("Vary")), "Origin")
+	}
+}
+
+func mustCors(config CorsConfig) *Cors {
+	cors, err := NewCors(config)
+	if err != nil {
+		panic(err)
+	}
+	return cors
+}
+
+func corsConn(method string, origin string, preflight bool) *fasthttp.RequestCtx {
+	conn := &fasthttp.RequestCtx{}
+	h := &conn.Request.Header
+	h.SetMethod(method)
+	if origin != "" {
+		h.Set("Origin", origin)
+	}
+	if preflight {
+		h.Set("Access-Control-Request-Method", "POST")
+	}
+	return conn
+}
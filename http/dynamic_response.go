@@ -12,6 +12,11 @@ time.
 */
 
 import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
 	"src.sqlkite.com/utils"
 	"src.sqlkite.com/utils/json"
 	"src.sqlkite.com/utils/log"
@@ -26,9 +31,51 @@ var (
 				Int("status", 400).
 				Finalize()
 
+	unauthorizedLogData = log.NewField().
+				Int("code", utils.RES_UNAUTHORIZED).
+				Int("status", 401).
+				Finalize()
+
+	forbiddenLogData = log.NewField().
+				Int("code", utils.RES_FORBIDDEN).
+				Int("status", 403).
+				Finalize()
+
+	methodNotAllowedLogData = log.NewField().
+				Int("code", utils.RES_METHOD_NOT_ALLOWED).
+				Int("status", 405).
+				Finalize()
+
+	conflictLogData = log.NewField().
+			Int("code", utils.RES_CONFLICT).
+			Int("status", 409).
+			Finalize()
+
+	payloadTooLargeLogData = log.NewField().
+				Int("code", utils.RES_PAYLOAD_TOO_LARGE).
+				Int("status", 413).
+				Finalize()
+
+	tooManyRequestsLogData = log.NewField().
+				Int("code", utils.RES_TOO_MANY_REQUESTS).
+				Int("status", 429).
+				Finalize()
+
 	OkLogData = log.NewField().
 			Int("status", 200).
 			Finalize()
+
+	createdLogData = log.NewField().
+			Int("status", 201).
+			Finalize()
+
+	redirectLogData = log.NewField().
+			Int("status", 302).
+			Finalize()
+
+	permanentRedirectLogData = log.NewField().
+					Int("status", 308).
+					Finalize()
 )
 
 // body isn't known until runtime, but we know the status
@@ -36,16 +83,38 @@ var (
 type DynamicResponse struct {
 	status  int
 	body    []byte
+	headers []header
 	logData log.Field
+
+	// only set when Ok or Created fail to serialize their data, in which
+	// case this is an ErrorIdResponse (see ErrorIdResponse.dynamic)
+	errorId string
 }
 
 func (r DynamicResponse) Write(conn *fasthttp.RequestCtx) {
 	conn.SetStatusCode(r.status)
+	if errorId := r.errorId; errorId != "" {
+		conn.Response.Header.SetBytesK([]byte("Error-Id"), errorId)
+	}
+	writeHeaders(conn, r.headers)
 	conn.SetBody(r.body)
 }
 
+func (r DynamicResponse) Header(key string, value string) DynamicResponse {
+	r.headers = withHeader(r.headers, key, value)
+	return r
+}
+
+func (r DynamicResponse) Cookie(cookie *fasthttp.Cookie) DynamicResponse {
+	return r.Header("Set-Cookie", cookie.String())
+}
+
 func (r DynamicResponse) EnhanceLog(logger log.Logger) log.Logger {
-	logger.Field(r.logData).Int("res", len(r.body))
+	logger.Field(r.logData)
+	if errorId := r.errorId; errorId != "" {
+		logger.String("eid", errorId)
+	}
+	logger.Int("res", len(r.body))
 	return logger
 }
 
@@ -68,7 +137,42 @@ func Validation(validator *validation.Result) DynamicResponse {
 	}
 }
 
-func Ok(data any) Response {
+func Unauthorized(data any) DynamicResponse {
+	return dynamicError(401, utils.RES_UNAUTHORIZED, "unauthorized", data, unauthorizedLogData)
+}
+
+func Forbidden(data any) DynamicResponse {
+	return dynamicError(403, utils.RES_FORBIDDEN, "forbidden", data, forbiddenLogData)
+}
+
+// The Allow header is set to the comma-separated list of allowed methods
+func MethodNotAllowed(allowed []string, data any) DynamicResponse {
+	res := dynamicError(405, utils.RES_METHOD_NOT_ALLOWED, "method not allowed", data, methodNotAllowedLogData)
+	res.headers = []header{{"Allow", strings.Join(allowed, ", ")}}
+	return res
+}
+
+func Conflict(data any) DynamicResponse {
+	return dynamicError(409, utils.RES_CONFLICT, "conflict", data, conflictLogData)
+}
+
+func PayloadTooLarge(data any) DynamicResponse {
+	return dynamicError(413, utils.RES_PAYLOAD_TOO_LARGE, "payload too large", data, payloadTooLargeLogData)
+}
+
+// The Retry-After header is set to retryAfter, rounded up to the
+// nearest second (and never less than 1 second)
+func TooManyRequests(retryAfter time.Duration, data any) DynamicResponse {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	res := dynamicError(429, utils.RES_TOO_MANY_REQUESTS, "too many requests", data, tooManyRequestsLogData)
+	res.headers = []header{{"Retry-After", strconv.Itoa(seconds)}}
+	return res
+}
+
+func Ok(data any) DynamicResponse {
 	var body []byte
 	if data != nil {
 		var err error
@@ -76,7 +180,7 @@ func Ok(data any) Response {
 			se := SerializationError()
 			logger := log.Error("res_ok_json").Err(err)
 			se.EnhanceLog(logger).Log()
-			return se
+			return se.dynamic()
 		}
 	}
 	return OkBytes(body)
@@ -89,3 +193,71 @@ func OkBytes(body []byte) DynamicResponse {
 		logData: OkLogData,
 	}
 }
+
+func Created(data any, location string) DynamicResponse {
+	var body []byte
+	if data != nil {
+		var err error
+		if body, err = json.Marshal(data); err != nil {
+			se := SerializationError()
+			logger := log.Error("res_created_json").Err(err)
+			se.EnhanceLog(logger).Log()
+			return se.dynamic()
+		}
+	}
+	return DynamicResponse{
+		status:  201,
+		body:    body,
+		headers: []header{{"Location", location}},
+		logData: createdLogData,
+	}
+}
+
+func Redirect(location string) DynamicResponse {
+	return DynamicResponse{
+		status:  302,
+		headers: []header{{"Location", location}},
+		logData: redirectLogData,
+	}
+}
+
+func PermanentRedirect(location string) DynamicResponse {
+	return DynamicResponse{
+		status:  308,
+		headers: []header{{"Location", location}},
+		logData: permanentRedirectLogData,
+	}
+}
+
+// Error responses where the status and code are known upfront, but
+// which can carry additional runtime data to help the client. If the data
+// can't be serialized, we log the failure and respond without it: the
+// status and code are still more useful to the client than a generic 500.
+func dynamicError(status int, code int, error string, data any, logData log.Field) DynamicResponse {
+	body, err := json.Marshal(struct {
+		Code  int    `json:"code"`
+		Error string `json:"error"`
+		Data  any    `json:"data,omitempty"`
+	}{
+		Code:  code,
+		Error: error,
+		Data:  data,
+	})
+
+	if err != nil {
+		log.Error("res_error_json").Err(err).Int("code", code).Log()
+		body, _ = json.Marshal(struct {
+			Code  int    `json:"code"`
+			Error string `json:"error"`
+		}{
+			Code:  code,
+			Error: error,
+		})
+	}
+
+	return DynamicResponse{
+		body:    body,
+		status:  status,
+		logData: logData,
+	}
+}
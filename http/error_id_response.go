@@ -23,21 +23,45 @@ var (
 type ErrorIdResponse struct {
 	errorId string
 	body    []byte
+	headers []header
 	logData log.Field
 }
 
 func (r ErrorIdResponse) Write(conn *fasthttp.RequestCtx) {
 	conn.SetStatusCode(500)
 	conn.Response.Header.SetBytesK([]byte("Error-Id"), r.errorId)
+	writeHeaders(conn, r.headers)
 	conn.SetBody(r.body)
 }
 
+func (r ErrorIdResponse) Header(key string, value string) ErrorIdResponse {
+	r.headers = withHeader(r.headers, key, value)
+	return r
+}
+
+func (r ErrorIdResponse) Cookie(cookie *fasthttp.Cookie) ErrorIdResponse {
+	return r.Header("Set-Cookie", cookie.String())
+}
+
+// The same response as a DynamicResponse, so that functions like Ok can
+// return a concrete type (which supports Header and Cookie) even when
+// they fail.
+func (r ErrorIdResponse) dynamic() DynamicResponse {
+	return DynamicResponse{
+		status:  500,
+		body:    r.body,
+		headers: r.headers,
+		logData: r.logData,
+		errorId: r.errorId,
+	}
+}
+
 func (r ErrorIdResponse) EnhanceLog(logger log.Logger) log.Logger {
 	logger.Field(r.logData).String("eid", r.errorId).Int("res", len(r.body))
 	return logger
 }
 
-func ServerError() Response {
+func ServerError() ErrorIdResponse {
 	errorId := uuid.String()
 
 	data := struct {
@@ -58,7 +82,7 @@ func ServerError() Response {
 	}
 }
 
-func SerializationError() Response {
+func SerializationError() ErrorIdResponse {
 	errorId := uuid.String()
 
 	data := struct {
@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"time"
 
 	"github.com/valyala/fasthttp"
@@ -14,10 +15,54 @@ type Env interface {
 	Error(string) log.Logger
 }
 
+type contextKey struct{}
+
+// The context for the request. For routes created with TimeoutHandler
+// or NoEnvTimeoutHandler, this is cancelled once the route's timeout
+// elapses and should be passed to any downstream work (e.g. via
+// pg.DB.WithContext). For other routes, this is context.Background().
+//
+// We purposefully don't derive from the RequestCtx (which is itself a
+// context.Context): it's cancelled as soon as the server starts to shut
+// down, and we'd rather in-flight requests get a chance to drain.
+func Context(conn *fasthttp.RequestCtx) context.Context {
+	if ctx, ok := conn.UserValue(contextKey{}).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
 func Handler[T Env](routeName string, loadEnv func(ctx *fasthttp.RequestCtx) (T, Response, error), next func(ctx *fasthttp.RequestCtx, env T) (Response, error)) func(ctx *fasthttp.RequestCtx) {
+	return handler(routeName, 0, loadEnv, next)
+}
+
+// Like Handler, but both loadEnv and next run with a Context(conn) that is
+// cancelled after timeout. The timeout isn't forced upon next: next (and
+// whatever it calls) has to honor the context. When next returns an error
+// after the timeout has elapsed, a RequestTimeout response is sent.
+func TimeoutHandler[T Env](routeName string, timeout time.Duration, loadEnv func(ctx *fasthttp.RequestCtx) (T, Response, error), next func(ctx *fasthttp.RequestCtx, env T) (Response, error)) func(ctx *fasthttp.RequestCtx) {
+	return handler(routeName, timeout, loadEnv, next)
+}
+
+func NoEnvHandler(routeName string, next func(ctx *fasthttp.RequestCtx) (Response, error)) func(ctx *fasthttp.RequestCtx) {
+	return noEnvHandler(routeName, 0, next)
+}
+
+// See TimeoutHandler
+func NoEnvTimeoutHandler(routeName string, timeout time.Duration, next func(ctx *fasthttp.RequestCtx) (Response, error)) func(ctx *fasthttp.RequestCtx) {
+	return noEnvHandler(routeName, timeout, next)
+}
+
+func handler[T Env](routeName string, timeout time.Duration, loadEnv func(ctx *fasthttp.RequestCtx) (T, Response, error), next func(ctx *fasthttp.RequestCtx, env T) (Response, error)) func(ctx *fasthttp.RequestCtx) {
 	return func(conn *fasthttp.RequestCtx) {
 		start := time.Now()
 
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			conn.SetUserValue(contextKey{}, ctx)
+		}
+
 		var haveEnv bool
 		var logger log.Logger
 		env, res, err := loadEnv(conn)
@@ -44,22 +89,28 @@ func Handler[T Env](routeName string, loadEnv func(ctx *fasthttp.RequestCtx) (T,
 			} else {
 				logger = log.Error("handler").Err(err)
 			}
-			res = ServerError()
+			res = errorResponse(conn)
 		}
 
 		res.Write(conn)
-		res.EnhanceLog(logger).
+		logger = res.EnhanceLog(logger).
 			String("route", routeName).
-			Int64("ms", time.Now().Sub(start).Milliseconds()).
-			Log()
+			Int64("ms", time.Now().Sub(start).Milliseconds())
+		enhanceAccessLog(conn, logger).Log()
 	}
 }
 
-func NoEnvHandler(routeName string, next func(ctx *fasthttp.RequestCtx) (Response, error)) func(ctx *fasthttp.RequestCtx) {
+func noEnvHandler(routeName string, timeout time.Duration, next func(ctx *fasthttp.RequestCtx) (Response, error)) func(ctx *fasthttp.RequestCtx) {
 	return func(conn *fasthttp.RequestCtx) {
 		start := time.Now()
 		var logger log.Logger
 
+		if timeout > 0 {
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			conn.SetUserValue(contextKey{}, ctx)
+		}
+
 		header := &conn.Response.Header
 		header.SetContentTypeBytes([]byte("application/json"))
 
@@ -68,14 +119,24 @@ func NoEnvHandler(routeName string, next func(ctx *fasthttp.RequestCtx) (Respons
 		if err == nil {
 			logger = log.Info("req")
 		} else {
-			res = ServerError()
+			res = errorResponse(conn)
 			logger = log.Error("handler").Err(err)
 		}
 
 		res.Write(conn)
-		res.EnhanceLog(logger).
+		logger = res.EnhanceLog(logger).
 			String("route", routeName).
-			Int64("ms", time.Now().Sub(start).Milliseconds()).
-			Log()
+			Int64("ms", time.Now().Sub(start).Milliseconds())
+		enhanceAccessLog(conn, logger).Log()
+	}
+}
+
+// When the request's context has timed out, the error is almost certainly
+// a consequence of it (e.g. a cancelled pg query), so we respond with a
+// timeout rather than a generic server error.
+func errorResponse(conn *fasthttp.RequestCtx) Response {
+	if Context(conn).Err() == context.DeadlineExceeded {
+		return RequestTimeout
 	}
+	return ServerError()
 }
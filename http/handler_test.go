@@ -3,6 +3,7 @@ package http
 import (
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/tests"
@@ -163,6 +164,101 @@ func Test_NoEnvHandler_LogsError(t *testing.T) {
 	assert.Equal(t, reqLog["eid"], string(errorId))
 }
 
+func Test_Handler_NoTimeout_Context(t *testing.T) {
+	testLoader := func(conn *fasthttp.RequestCtx) (*TestEnv, Response, error) {
+		return testEnv(203), nil, nil
+	}
+
+	conn := &fasthttp.RequestCtx{}
+	Handler("", testLoader, func(conn *fasthttp.RequestCtx, env *TestEnv) (Response, error) {
+		ctx := Context(conn)
+		_, hasDeadline := ctx.Deadline()
+		assert.False(t, hasDeadline)
+		assert.Nil(t, ctx.Err())
+		return OkBytes(nil), nil
+	})(conn)
+	assert.Equal(t, conn.Response.StatusCode(), 200)
+}
+
+func Test_TimeoutHandler_Context(t *testing.T) {
+	var loaderDeadline time.Time
+	testLoader := func(conn *fasthttp.RequestCtx) (*TestEnv, Response, error) {
+		loaderDeadline, _ = Context(conn).Deadline()
+		return testEnv(204), nil, nil
+	}
+
+	conn := &fasthttp.RequestCtx{}
+	TimeoutHandler("", time.Minute, testLoader, func(conn *fasthttp.RequestCtx, env *TestEnv) (Response, error) {
+		deadline, ok := Context(conn).Deadline()
+		assert.True(t, ok)
+		assert.Equal(t, deadline, loaderDeadline)
+		assert.True(t, time.Until(deadline) > 50*time.Second)
+		return OkBytes(nil), nil
+	})(conn)
+	assert.Equal(t, conn.Response.StatusCode(), 200)
+}
+
+func Test_TimeoutHandler_TimesOut(t *testing.T) {
+	testLoader := func(conn *fasthttp.RequestCtx) (*TestEnv, Response, error) {
+		return testEnv(205), nil, nil
+	}
+
+	conn := &fasthttp.RequestCtx{}
+	logged := tests.CaptureLog(func() {
+		TimeoutHandler("slow", 10*time.Millisecond, testLoader, func(conn *fasthttp.RequestCtx, env *TestEnv) (Response, error) {
+			// simulate downstream work honoring the context
+			ctx := Context(conn)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})(conn)
+	})
+
+	res := conn.Response
+	assert.Equal(t, res.StatusCode(), 503)
+	assertCode(t, conn, 2011)
+
+	reqLog := log.KvParse(logged)
+	assert.Equal(t, reqLog["l"], "error")
+	assert.Equal(t, reqLog["c"], "handler")
+	assert.Equal(t, reqLog["code"], "2011")
+	assert.Equal(t, reqLog["status"], "503")
+	assert.Equal(t, reqLog["route"], "slow")
+	assert.Equal(t, reqLog["err"], `"context deadline exceeded"`)
+}
+
+func Test_TimeoutHandler_ErrorBeforeTimeout(t *testing.T) {
+	testLoader := func(conn *fasthttp.RequestCtx) (*TestEnv, Response, error) {
+		return testEnv(206), nil, nil
+	}
+
+	conn := &fasthttp.RequestCtx{}
+	tests.CaptureLog(func() {
+		TimeoutHandler("", time.Minute, testLoader, func(conn *fasthttp.RequestCtx, env *TestEnv) (Response, error) {
+			return nil, errors.New("Not Over 9000!")
+		})(conn)
+	})
+	assert.Equal(t, conn.Response.StatusCode(), 500)
+	assertCode(t, conn, 2001)
+}
+
+func Test_NoEnvTimeoutHandler_TimesOut(t *testing.T) {
+	conn := &fasthttp.RequestCtx{}
+	logged := tests.CaptureLog(func() {
+		NoEnvTimeoutHandler("slow", 10*time.Millisecond, func(conn *fasthttp.RequestCtx) (Response, error) {
+			ctx := Context(conn)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})(conn)
+	})
+
+	assert.Equal(t, conn.Response.StatusCode(), 503)
+	assertCode(t, conn, 2011)
+
+	reqLog := log.KvParse(logged)
+	assert.Equal(t, reqLog["code"], "2011")
+	assert.Equal(t, reqLog["route"], "slow")
+}
+
 type TestEnv struct {
 	id       int
 	released bool
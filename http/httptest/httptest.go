@@ -0,0 +1,218 @@
+//go:build !release
+
+/*
+Helpers to test handlers created with http.Handler and http.NoEnvHandler
+without going through the network:
+
+	res := httptest.Request().
+		Method("POST").
+		Path("/v1/users").
+		Json(map[string]any{"name": "leto"}).
+		Run(handler)
+
+	assert.Equal(t, res.Status, 201)
+	assert.Equal(t, res.Json.String("name"), "leto")
+	assert.Equal(t, res.Log["route"], "create_user")
+
+Running a request captures everything logged while the handler executes,
+so tests using this shouldn't be run in parallel.
+*/
+package httptest
+
+import (
+	"bytes"
+	"net"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils/json"
+	"src.sqlkite.com/utils/log"
+	"src.sqlkite.com/utils/typed"
+)
+
+type RequestBuilder struct {
+	req        fasthttp.Request
+	remoteIP   net.IP
+	userValues map[string]any
+}
+
+func Request() *RequestBuilder {
+	rb := &RequestBuilder{remoteIP: net.IPv4(127, 0, 0, 1)}
+	rb.req.Header.SetMethod("GET")
+	rb.req.SetRequestURI("/")
+	return rb
+}
+
+func (rb *RequestBuilder) Method(method string) *RequestBuilder {
+	rb.req.Header.SetMethod(method)
+	return rb
+}
+
+// Sets the path, which may include a query string. Replaces any
+// previously added Query.
+func (rb *RequestBuilder) Path(path string) *RequestBuilder {
+	rb.req.SetRequestURI(path)
+	return rb
+}
+
+// Adds a query string parameter; can be called multiple times for the
+// same key.
+func (rb *RequestBuilder) Query(key string, value string) *RequestBuilder {
+	rb.req.URI().QueryArgs().Add(key, value)
+	return rb
+}
+
+func (rb *RequestBuilder) Header(key string, value string) *RequestBuilder {
+	rb.req.Header.Set(key, value)
+	return rb
+}
+
+func (rb *RequestBuilder) RemoteIP(ip string) *RequestBuilder {
+	rb.remoteIP = net.ParseIP(ip)
+	return rb
+}
+
+// Sets a user value on the RequestCtx, as a router would for route
+// parameters.
+func (rb *RequestBuilder) UserValue(key string, value any) *RequestBuilder {
+	if rb.userValues == nil {
+		rb.userValues = make(map[string]any)
+	}
+	rb.userValues[key] = value
+	return rb
+}
+
+// Sets the body as-is
+func (rb *RequestBuilder) Body(body string) *RequestBuilder {
+	rb.req.SetBodyString(body)
+	return rb
+}
+
+// Sets the body to the JSON-encoded value (strings and []byte are used
+// as-is) along with a JSON Content-Type. Panics if value can't be encoded.
+func (rb *RequestBuilder) Json(value any) *RequestBuilder {
+	var body []byte
+	switch v := value.(type) {
+	case string:
+		body = []byte(v)
+	case []byte:
+		body = v
+	default:
+		var err error
+		if body, err = json.Marshal(value); err != nil {
+			panic(err)
+		}
+	}
+	rb.req.Header.SetContentType("application/json")
+	rb.req.SetBody(body)
+	return rb
+}
+
+// Creates the RequestCtx. Useful when the handler isn't a
+// fasthttp.RequestHandler (e.g. testing a Cors.Preflight or a
+// RateLimiter directly). The builder can be reused.
+func (rb *RequestBuilder) Conn() *fasthttp.RequestCtx {
+	var req fasthttp.Request
+	rb.req.CopyTo(&req)
+
+	conn := &fasthttp.RequestCtx{}
+	conn.Init(&req, &net.TCPAddr{IP: rb.remoteIP}, nil)
+	for k, v := range rb.userValues {
+		conn.SetUserValue(k, v)
+	}
+	return conn
+}
+
+// Executes the handler against the request and captures the response
+// and the log output.
+func (rb *RequestBuilder) Run(handler fasthttp.RequestHandler) *Response {
+	conn := rb.Conn()
+
+	var out bytes.Buffer
+	original := log.Out
+	log.Out = &out
+	func() {
+		defer func() { log.Out = original }()
+		handler(conn)
+	}()
+
+	return NewResponse(conn, out.String())
+}
+
+type Response struct {
+	Status int
+	Body   string
+
+	// The parsed body when the response is JSON, else nil
+	Json typed.Typed
+
+	// The last line logged, which, for http.Handler and http.NoEnvHandler,
+	// is the request line.
+	Log map[string]string
+
+	// Every line logged, in order
+	Logs []map[string]string
+
+	conn *fasthttp.RequestCtx
+}
+
+// Builds a Response from an already-executed RequestCtx and its logged
+// output (which can be empty).
+func NewResponse(conn *fasthttp.RequestCtx, logged string) *Response {
+	res := &conn.Response
+	body := string(res.Body())
+
+	var logs []map[string]string
+	for _, line := range strings.Split(logged, "\n") {
+		if line != "" {
+			logs = append(logs, log.KvParse(line))
+		}
+	}
+
+	var lastLog map[string]string
+	if l := len(logs); l > 0 {
+		lastLog = logs[l-1]
+	}
+
+	var js typed.Typed
+	if len(body) > 0 && bytes.HasPrefix(res.Header.ContentType(), []byte("application/json")) {
+		// a handler emitting invalid JSON is something tests should see,
+		// not something that should blow up the helper
+		js, _ = typed.JsonString(body)
+	}
+
+	return &Response{
+		conn:   conn,
+		Body:   body,
+		Json:   js,
+		Log:    lastLog,
+		Logs:   logs,
+		Status: res.StatusCode(),
+	}
+}
+
+// The value of the response header, or "" if it isn't set
+func (r *Response) Header(key string) string {
+	return string(r.conn.Response.Header.Peek(key))
+}
+
+// All response headers. Keys are in fasthttp's normalized form
+// (e.g. Content-Type, Ratelimit-Limit).
+func (r *Response) Headers() map[string]string {
+	headers := make(map[string]string)
+	r.conn.Response.Header.VisitAll(func(key []byte, value []byte) {
+		headers[string(key)] = string(value)
+	})
+	return headers
+}
+
+// The value of the Set-Cookie header for the given cookie, or "" if it
+// wasn't set
+func (r *Response) Cookie(key string) string {
+	return string(r.conn.Response.Header.PeekCookie(key))
+}
+
+// The RequestCtx the handler ran with
+func (r *Response) Conn() *fasthttp.RequestCtx {
+	return r.conn
+}
@@ -0,0 +1,98 @@
+package httptest
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/http"
+	"src.sqlkite.com/utils/log"
+)
+
+func Test_Request_Defaults(t *testing.T) {
+	conn := Request().Conn()
+	assert.Equal(t, string(conn.Method()), "GET")
+	assert.Equal(t, string(conn.Path()), "/")
+	assert.Equal(t, conn.RemoteIP().String(), "127.0.0.1")
+	assert.Equal(t, len(conn.Request.Body()), 0)
+}
+
+func Test_Request_Builder(t *testing.T) {
+	conn := Request().
+		Method("PUT").
+		Path("/v1/users?a=1").
+		Query("b", "2").
+		Query("b", "3").
+		Header("X-Power", "9001").
+		RemoteIP("9.9.9.9").
+		UserValue("id", "leto").
+		Json(map[string]any{"over": 9000}).
+		Conn()
+
+	assert.Equal(t, string(conn.Method()), "PUT")
+	assert.Equal(t, string(conn.Path()), "/v1/users")
+	assert.Equal(t, string(conn.QueryArgs().QueryString()), "a=1&b=2&b=3")
+	assert.Equal(t, string(conn.Request.Header.Peek("X-Power")), "9001")
+	assert.Equal(t, string(conn.Request.Header.ContentType()), "application/json")
+	assert.Equal(t, conn.RemoteIP().String(), "9.9.9.9")
+	assert.Equal(t, conn.UserValue("id").(string), "leto")
+	assert.Equal(t, string(conn.Request.Body()), `{"over":9000}`)
+}
+
+func Test_Request_Body(t *testing.T) {
+	conn := Request().Json(`{"raw":true}`).Conn()
+	assert.Equal(t, string(conn.Request.Body()), `{"raw":true}`)
+
+	conn = Request().Body("a=b").Conn()
+	assert.Equal(t, string(conn.Request.Body()), "a=b")
+	assert.Equal(t, len(conn.Request.Header.ContentType()), 0)
+}
+
+func Test_Run_Ok(t *testing.T) {
+	handler := http.NoEnvHandler("echo", func(conn *fasthttp.RequestCtx) (http.Response, error) {
+		conn.Response.Header.Set("X-Power", "9001")
+		return http.Ok(map[string]any{"path": string(conn.Path())}), nil
+	})
+
+	res := Request().Path("/hello").Run(handler)
+	assert.Equal(t, res.Status, 200)
+	assert.Equal(t, res.Body, `{"path":"/hello"}`)
+	assert.Equal(t, res.Json.String("path"), "/hello")
+	assert.Equal(t, res.Header("X-Power"), "9001")
+	assert.Equal(t, res.Headers()["Content-Type"], "application/json")
+	assert.Equal(t, res.Log["route"], "echo")
+	assert.Equal(t, res.Log["status"], "200")
+	assert.Equal(t, len(res.Logs), 1)
+}
+
+func Test_Run_Error(t *testing.T) {
+	handler := http.NoEnvHandler("fail", func(conn *fasthttp.RequestCtx) (http.Response, error) {
+		log.Info("before").Log()
+		return nil, errors.New("Not Over 9000!")
+	})
+
+	res := Request().Run(handler)
+	assert.Equal(t, res.Status, 500)
+	assert.Equal(t, res.Json.Int("code"), 2001)
+	assert.Equal(t, res.Header("Error-Id"), res.Log["eid"])
+	assert.Equal(t, len(res.Logs), 2)
+	assert.Equal(t, res.Logs[0]["c"], "before")
+	assert.Equal(t, res.Log["c"], "handler")
+	assert.Equal(t, res.Log["err"], `"Not Over 9000!"`)
+}
+
+func Test_Run_Cookie(t *testing.T) {
+	handler := http.NoEnvHandler("", func(conn *fasthttp.RequestCtx) (http.Response, error) {
+		cookie := fasthttp.AcquireCookie()
+		defer fasthttp.ReleaseCookie(cookie)
+		cookie.SetKey("session")
+		cookie.SetValue("abc")
+		return http.OkBytes(nil).Cookie(cookie), nil
+	})
+
+	res := Request().Run(handler)
+	assert.Equal(t, res.Cookie("session"), "session=abc")
+	assert.Equal(t, res.Cookie("other"), "")
+	assert.Nil(t, res.Json)
+}
@@ -0,0 +1,225 @@
+package http
+
+/*
+Per-route rate limiting using in-memory token buckets. Each limiter
+has its own set of buckets, keyed by whatever the KeyExtractor
+returns (e.g. the client IP or an API key).
+
+A bucket holds up to Limit tokens and refills at Limit tokens per
+Window. Every request takes a token; when the bucket is empty,
+the request is limited.
+
+Handlers call Check at the top of the route:
+
+	if res := limiter.Check(conn); res != nil {
+		return res, nil
+	}
+
+Allowed requests get RateLimit-* headers written to the response
+directly. Limited requests get a 429 which includes the same headers
+(plus Retry-After) and which adds the limited key to the request log.
+RateLimit-Reset is always the number of seconds until the bucket is
+full again, while Retry-After is the number of seconds until the next
+token is available.
+
+Buckets live until Prune removes them, which the application should
+call periodically.
+*/
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils/concurrent"
+	"src.sqlkite.com/utils/log"
+)
+
+type RateLimitConfig struct {
+	// the number of requests allowed within the window (which is also
+	// the maximum burst)
+	Limit uint32 `json:"limit"`
+
+	// in seconds
+	Window uint32 `json:"window"`
+}
+
+// Returns the key to rate limit the request by. An empty key
+// means the request isn't limited.
+type KeyExtractor func(conn *fasthttp.RequestCtx) string
+
+func ClientIPKey(conn *fasthttp.RequestCtx) string {
+	return conn.RemoteIP().String()
+}
+
+func HeaderKey(name string) KeyExtractor {
+	return func(conn *fasthttp.RequestCtx) string {
+		return string(conn.Request.Header.Peek(name))
+	}
+}
+
+type RateLimiter struct {
+	// the limit as a string, since we write it in every response
+	limitHeader string
+
+	// tokens
+	limit float64
+
+	// tokens per second
+	rate float64
+
+	key     KeyExtractor
+	buckets concurrent.Map[*bucket]
+
+	// for tests
+	now func() time.Time
+}
+
+func NewRateLimiter(config RateLimitConfig, key KeyExtractor) *RateLimiter {
+	limit := config.Limit
+	if limit == 0 {
+		limit = 60
+	}
+
+	window := config.Window
+	if window == 0 {
+		window = 60
+	}
+
+	l := &RateLimiter{
+		key:         key,
+		now:         time.Now,
+		limit:       float64(limit),
+		limitHeader: strconv.Itoa(int(limit)),
+		rate:        float64(limit) / float64(window),
+	}
+
+	l.buckets = concurrent.NewMap[*bucket](func(key string) (*bucket, error) {
+		return &bucket{tokens: l.limit, last: l.now()}, nil
+	})
+	return l
+}
+
+// Returns nil if the request is allowed, else a TooManyRequests response
+func (l *RateLimiter) Check(conn *fasthttp.RequestCtx) Response {
+	key := l.key(conn)
+	if key == "" {
+		return nil
+	}
+
+	now := l.now()
+	limit, rate := l.limit, l.rate
+
+	var allowed bool
+	var tokens float64
+	for {
+		// our loader never fails
+		b, _ := l.buckets.Get(key)
+		var live bool
+		if allowed, tokens, live = b.take(now, limit, rate); live {
+			break
+		}
+		// pruned between the Get and the take, the next Get will load a
+		// new bucket
+	}
+
+	remainingHeader := strconv.Itoa(int(tokens))
+	resetHeader := strconv.Itoa(int(math.Ceil((limit - tokens) / rate)))
+
+	if allowed {
+		h := &conn.Response.Header
+		h.Set("RateLimit-Limit", l.limitHeader)
+		h.Set("RateLimit-Remaining", remainingHeader)
+		h.Set("RateLimit-Reset", resetHeader)
+		return nil
+	}
+
+	return rateLimitedResponse{
+		key: key,
+		DynamicResponse: TooManyRequests(seconds((1-tokens)/rate), nil).
+			Header("RateLimit-Limit", l.limitHeader).
+			Header("RateLimit-Remaining", remainingHeader).
+			Header("RateLimit-Reset", resetHeader),
+	}
+}
+
+// Removes buckets which have fully refilled. These are
+// indistinguishable from a new bucket, so removing them is safe.
+func (l *RateLimiter) Prune() int {
+	now := l.now()
+	limit, rate := l.limit, l.rate
+	return l.buckets.DeleteIf(func(key string, b *bucket) bool {
+		return b.prune(now, limit, rate)
+	})
+}
+
+type bucket struct {
+	sync.Mutex
+	tokens float64
+	last   time.Time
+
+	// set when pruned, so that a request which got the bucket just
+	// before it was removed doesn't take a token that'll be forgotten
+	pruned bool
+}
+
+// Takes a token. Returns whether a token was available and how many
+// are left. The last value is false if the bucket was pruned, in which
+// case nothing was taken and the caller should get the bucket again.
+func (b *bucket) take(now time.Time, limit float64, rate float64) (bool, float64, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.pruned {
+		return false, 0, false
+	}
+
+	tokens := b.refill(now, limit, rate)
+	if tokens < 1 {
+		return false, tokens, true
+	}
+
+	tokens -= 1
+	b.tokens = tokens
+	return true, tokens, true
+}
+
+// Marks the bucket as pruned if it's full. Called by the map while the
+// shard is locked, so the bucket is removed before anyone else can get it.
+func (b *bucket) prune(now time.Time, limit float64, rate float64) bool {
+	b.Lock()
+	defer b.Unlock()
+	if b.refill(now, limit, rate) == limit {
+		b.pruned = true
+	}
+	return b.pruned
+}
+
+// must be called under lock
+func (b *bucket) refill(now time.Time, limit float64, rate float64) float64 {
+	tokens := b.tokens
+	if elapsed := now.Sub(b.last).Seconds(); elapsed > 0 {
+		tokens += elapsed * rate
+		if tokens > limit {
+			tokens = limit
+		}
+		b.last = now
+	}
+	b.tokens = tokens
+	return tokens
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+type rateLimitedResponse struct {
+	DynamicResponse
+	key string
+}
+
+func (r rateLimitedResponse) EnhanceLog(logger log.Logger) log.Logger {
+	return r.DynamicResponse.EnhanceLog(logger).String("rl_key", r.key)
+}
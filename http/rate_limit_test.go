@@ -0,0 +1,179 @@
+package http
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests/assert"
+)
+
+func Test_RateLimiter_Defaults(t *testing.T) {
+	l := NewRateLimiter(RateLimitConfig{}, ClientIPKey)
+	assert.Equal(t, l.limit, 60)
+	assert.Equal(t, l.rate, 1)
+	assert.Equal(t, l.limitHeader, "60")
+}
+
+func Test_RateLimiter_Allowed(t *testing.T) {
+	l, _ := testRateLimiter(3, 3)
+
+	for i, remaining := range []string{"2", "1", "0"} {
+		conn := rateLimitConn("k1")
+		assert.Nil(t, l.Check(conn))
+
+		h := &conn.Response.Header
+		assert.Equal(t, string(h.Peek("RateLimit-Limit")), "3")
+		assert.Equal(t, string(h.Peek("RateLimit-Remaining")), remaining)
+		assert.Equal(t, string(h.Peek("RateLimit-Reset")), []string{"1", "2", "3"}[i])
+	}
+}
+
+func Test_RateLimiter_Limited(t *testing.T) {
+	l, _ := testRateLimiter(2, 10)
+
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+
+	res := read(l.Check(rateLimitConn("k1")))
+	assert.Equal(t, res.status, 429)
+	assert.Equal(t, res.json.Int("code"), 2008)
+	assert.Equal(t, res.headers["Retry-After"], "5")
+	assert.Equal(t, res.headers["Ratelimit-Limit"], "2")
+	assert.Equal(t, res.headers["Ratelimit-Remaining"], "0")
+	assert.Equal(t, res.headers["Ratelimit-Reset"], "10")
+	assert.Equal(t, res.log["code"], "2008")
+	assert.Equal(t, res.log["status"], "429")
+	assert.Equal(t, res.log["rl_key"], "k1")
+
+	// different key, different bucket
+	assert.Nil(t, l.Check(rateLimitConn("k2")))
+}
+
+func Test_RateLimiter_Refill(t *testing.T) {
+	l, clock := testRateLimiter(2, 2)
+
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+	assert.NotNil(t, l.Check(rateLimitConn("k1")))
+
+	*clock = clock.Add(500 * time.Millisecond)
+	assert.NotNil(t, l.Check(rateLimitConn("k1")))
+
+	*clock = clock.Add(500 * time.Millisecond)
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+	assert.NotNil(t, l.Check(rateLimitConn("k1")))
+
+	// never refills more than the limit
+	*clock = clock.Add(time.Hour)
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+	assert.Nil(t, l.Check(rateLimitConn("k1")))
+	assert.NotNil(t, l.Check(rateLimitConn("k1")))
+}
+
+func Test_RateLimiter_EmptyKey(t *testing.T) {
+	l, _ := testRateLimiter(1, 1)
+	for i := 0; i < 5; i++ {
+		assert.Nil(t, l.Check(rateLimitConn("")))
+	}
+}
+
+func Test_RateLimiter_Prune(t *testing.T) {
+	l, clock := testRateLimiter(2, 2)
+	l.Check(rateLimitConn("k1"))
+	l.Check(rateLimitConn("k2"))
+	l.Check(rateLimitConn("k2"))
+
+	*clock = clock.Add(time.Second)
+	assert.Equal(t, l.Prune(), 1) // k1 is full
+
+	*clock = clock.Add(time.Second)
+	assert.Equal(t, l.Prune(), 1) // k2 is full
+	assert.Equal(t, l.Prune(), 0)
+}
+
+func Test_RateLimiter_Reset(t *testing.T) {
+	l, clock := testRateLimiter(4, 8)
+
+	// RateLimit-Reset is the time until the bucket is full, allowed or not
+	for _, reset := range []string{"2", "4", "6", "8"} {
+		conn := rateLimitConn("k1")
+		assert.Nil(t, l.Check(conn))
+		assert.Equal(t, string(conn.Response.Header.Peek("RateLimit-Reset")), reset)
+	}
+
+	*clock = clock.Add(time.Second)
+	res := read(l.Check(rateLimitConn("k1")))
+	assert.Equal(t, res.status, 429)
+	assert.Equal(t, res.headers["Ratelimit-Reset"], "7")
+	assert.Equal(t, res.headers["Retry-After"], "1")
+}
+
+func Test_RateLimiter_PrunedBucket(t *testing.T) {
+	l, _ := testRateLimiter(2, 2)
+
+	// a request got the bucket just before it was pruned
+	b, _ := l.buckets.Get("k1")
+	assert.Equal(t, l.Prune(), 1)
+	_, _, live := b.take(l.now(), l.limit, l.rate)
+	assert.False(t, live)
+
+	// pruning concurrently with requests never allows more than the limit
+	var allowed int32
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				l.Prune()
+			}
+		}
+	}()
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if l.Check(rateLimitConn("k2")) == nil {
+				atomic.AddInt32(&allowed, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	close(stop)
+	assert.Equal(t, int(allowed), 2)
+}
+
+func Test_RateLimiter_ClientIPKey(t *testing.T) {
+	conn := &fasthttp.RequestCtx{}
+	conn.Init(&fasthttp.Request{}, &net.TCPAddr{IP: net.IPv4(10, 0, 0, 3)}, nil)
+	assert.Equal(t, ClientIPKey(conn), "10.0.0.3")
+}
+
+func Test_RateLimiter_HeaderKey(t *testing.T) {
+	conn := &fasthttp.RequestCtx{}
+	assert.Equal(t, HeaderKey("X-Api-Key")(conn), "")
+
+	conn.Request.Header.Set("X-Api-Key", "k-9001")
+	assert.Equal(t, HeaderKey("X-Api-Key")(conn), "k-9001")
+}
+
+func testRateLimiter(limit uint32, window uint32) (*RateLimiter, *time.Time) {
+	clock := time.Unix(1700000000, 0)
+	l := NewRateLimiter(RateLimitConfig{Limit: limit, Window: window}, HeaderKey("X-Key"))
+	l.now = func() time.Time { return clock }
+	return l, &clock
+}
+
+func rateLimitConn(key string) *fasthttp.RequestCtx {
+	conn := &fasthttp.RequestCtx{}
+	if key != "" {
+		conn.Request.Header.Set("X-Key", key)
+	}
+	return conn
+}
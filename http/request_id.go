@@ -0,0 +1,65 @@
+package http
+
+/*
+Generates request ids using utils.EncodeRequestId. Each instance of a
+service should be given its own instance id so that request ids are
+unique across instances.
+
+When the generator trusts incoming request ids (e.g. because it sits
+behind our own load balancer), a well-formed X-Request-Id header is
+used as-is, so that a single id can be followed across services.
+*/
+
+import (
+	"sync/atomic"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+)
+
+type RequestIdGenerator struct {
+	counter       uint32
+	instanceId    uint8
+	trustIncoming bool
+}
+
+func NewRequestIdGenerator(instanceId uint8, trustIncoming bool) *RequestIdGenerator {
+	return &RequestIdGenerator{
+		instanceId:    instanceId,
+		trustIncoming: trustIncoming,
+	}
+}
+
+func (g *RequestIdGenerator) InstanceId() uint8 {
+	return g.instanceId
+}
+
+// Generates a new request id. The counter wraps around after 2^32 requests.
+func (g *RequestIdGenerator) Next() string {
+	return utils.EncodeRequestId(atomic.AddUint32(&g.counter, 1), g.instanceId)
+}
+
+// Returns the request's X-Request-Id if we trust incoming ids and the
+// value is well-formed, else generates a new id.
+func (g *RequestIdGenerator) FromRequest(conn *fasthttp.RequestCtx) string {
+	if g.trustIncoming {
+		if incoming := conn.Request.Header.Peek("X-Request-Id"); validIncomingRequestId(incoming) {
+			return string(incoming)
+		}
+	}
+	return g.Next()
+}
+
+// Incoming ids might come from other systems and might not be in our
+// format. We accept anything reasonably short and safe to log.
+func validIncomingRequestId(id []byte) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !((c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
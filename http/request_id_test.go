@@ -0,0 +1,78 @@
+package http
+
+import (
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils"
+)
+
+func Test_RequestIdGenerator_Next(t *testing.T) {
+	g := NewRequestIdGenerator(7, false)
+	assert.Equal(t, g.InstanceId(), 7)
+
+	for i := uint32(1); i < 10; i++ {
+		requestId, instanceId, err := utils.DecodeRequestId(g.Next())
+		assert.Nil(t, err)
+		assert.Equal(t, requestId, i)
+		assert.Equal(t, instanceId, 7)
+	}
+}
+
+func Test_RequestIdGenerator_Concurrent(t *testing.T) {
+	g := NewRequestIdGenerator(1, false)
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	seen := make(map[string]struct{}, 1000)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := g.Next()
+				lock.Lock()
+				seen[id] = struct{}{}
+				lock.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, len(seen), 1000)
+}
+
+func Test_RequestIdGenerator_FromRequest_Untrusted(t *testing.T) {
+	g := NewRequestIdGenerator(2, false)
+	conn := &fasthttp.RequestCtx{}
+	conn.Request.Header.Set("X-Request-Id", "upstream-1")
+
+	id := g.FromRequest(conn)
+	assert.NotEqual(t, id, "upstream-1")
+	_, instanceId, err := utils.DecodeRequestId(id)
+	assert.Nil(t, err)
+	assert.Equal(t, instanceId, 2)
+}
+
+func Test_RequestIdGenerator_FromRequest_Trusted(t *testing.T) {
+	g := NewRequestIdGenerator(3, true)
+
+	conn := &fasthttp.RequestCtx{}
+	conn.Request.Header.Set("X-Request-Id", "upstream-1_A")
+	assert.Equal(t, g.FromRequest(conn), "upstream-1_A")
+
+	// missing or invalid ids are replaced
+	for _, incoming := range []string{"", "has space", "new\nline", "quote\"", strings.Repeat("a", 65)} {
+		conn := &fasthttp.RequestCtx{}
+		if incoming != "" {
+			conn.Request.Header.Set("X-Request-Id", incoming)
+		}
+		id := g.FromRequest(conn)
+		_, instanceId, err := utils.DecodeRequestId(id)
+		assert.Nil(t, err)
+		assert.Equal(t, instanceId, 3)
+	}
+}
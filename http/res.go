@@ -8,6 +8,11 @@ Our HTTP responses must do two things:
 Note that RequestCtx has various ways of writing the body (e.g.
 using a []byte directly, or maybe an io.Reader). It's up to
 each response to figure out how it's going to interact with it.
+
+Responses can also carry extra headers (and cookies, which are
+nothing more than Set-Cookie headers). Responses are values and
+static responses are shared, so adding a header always copies the
+existing headers rather than appending into a shared slice.
 */
 
 import (
@@ -21,3 +26,24 @@ type Response interface {
 	EnhanceLog(logger log.Logger) log.Logger
 	Write(conn *fasthttp.RequestCtx)
 }
+
+type header struct {
+	key   string
+	value string
+}
+
+func withHeader(headers []header, key string, value string) []header {
+	h := make([]header, len(headers), len(headers)+1)
+	copy(h, headers)
+	return append(h, header{key: key, value: value})
+}
+
+func writeHeaders(conn *fasthttp.RequestCtx, headers []header) {
+	if headers == nil {
+		return
+	}
+	h := &conn.Response.Header
+	for _, header := range headers {
+		h.Set(header.key, header.value)
+	}
+}
@@ -2,6 +2,7 @@ package http
 
 import (
 	"testing"
+	"time"
 
 	"src.sqlkite.com/tests/assert"
 	"src.sqlkite.com/utils/log"
@@ -12,10 +13,11 @@ import (
 )
 
 type TestResponse struct {
-	status int
-	body   string
-	json   typed.Typed
-	log    map[string]string
+	status  int
+	body    string
+	json    typed.Typed
+	log     map[string]string
+	headers map[string]string
 }
 
 func Test_Ok_NoBody(t *testing.T) {
@@ -85,10 +87,18 @@ func Test_ServerError(t *testing.T) {
 
 func Test_Validation(t *testing.T) {
 	result := validation.NewResult(5)
-	result.InvalidField([]string{"field1"}, validation.Required, nil)
-	result.InvalidField([]string{"field2"}, validation.Required, 331)
-	result.Invalid(validation.InvalidStringType, nil)
-	result.Invalid(validation.InvalidStringType, map[string]any{"over": 9000})
+	result.AddInvalidField(validation.Field{Name: "field1", Flat: "field1"}, validation.Required())
+	result.AddInvalidField(validation.Field{Name: "field2", Flat: "field2"}, validation.Invalid{
+		Code:  1001,
+		Error: "required",
+		Data:  331,
+	})
+	result.AddInvalidField(validation.Field{Name: "field3", Flat: "field3"}, validation.InvalidStringType())
+	result.AddInvalidField(validation.Field{Name: "field4", Flat: "field4"}, validation.Invalid{
+		Code:  1002,
+		Error: "must be a string",
+		Data:  map[string]any{"over": 9000},
+	})
 
 	res := read(Validation(result))
 	assert.Equal(t, res.status, 400)
@@ -98,36 +108,231 @@ func Test_Validation(t *testing.T) {
 	invalid := res.json.Objects("invalid")
 	assert.Equal(t, len(invalid), 4)
 	assert.Equal(t, invalid[0].Int("code"), 1001)
-	assert.Equal(t, invalid[0]["fields"].([]interface{})[0].(string), "field1")
+	assert.Equal(t, invalid[0].String("field"), "field1")
 	assert.Equal(t, invalid[0].String("error"), "required")
 	assert.Nil(t, invalid[0].Object("data"))
 
 	assert.Equal(t, invalid[1].Int("code"), 1001)
-	assert.Equal(t, invalid[1]["fields"].([]interface{})[0].(string), "field2")
+	assert.Equal(t, invalid[1].String("field"), "field2")
 	assert.Equal(t, invalid[1].String("error"), "required")
 	assert.Equal(t, invalid[1].Int("data"), 331)
 
 	assert.Equal(t, invalid[2].Int("code"), 1002)
-	assert.Equal(t, invalid[2].String("fields"), "")
+	assert.Equal(t, invalid[2].String("field"), "field3")
 	assert.Equal(t, invalid[2].String("error"), "must be a string")
 	assert.Nil(t, invalid[2].Object("data"))
 
 	assert.Equal(t, invalid[3].Int("code"), 1002)
+	assert.Equal(t, invalid[3].String("field"), "field4")
 	assert.Equal(t, invalid[3].String("error"), "must be a string")
 	assert.Equal(t, invalid[3].Object("data").Int("over"), 9000)
-	_, exists := invalid[3]["fields"]
-	assert.False(t, exists)
 
-	assert.Equal(t, res.log["res"], "292")
+	assert.Equal(t, res.log["res"], "320")
 	assert.Equal(t, res.log["code"], "2004")
 	assert.Equal(t, res.log["status"], "400")
 }
 
+func Test_Unauthorized(t *testing.T) {
+	res := read(Unauthorized(nil))
+	assert.Equal(t, res.status, 401)
+	assert.Equal(t, res.body, `{"code":2005,"error":"unauthorized"}`)
+	assert.Equal(t, res.log["res"], "36")
+	assert.Equal(t, res.log["code"], "2005")
+	assert.Equal(t, res.log["status"], "401")
+
+	res = read(Unauthorized(map[string]any{"reason": "expired"}))
+	assert.Equal(t, res.status, 401)
+	assert.Equal(t, res.json.Int("code"), 2005)
+	assert.Equal(t, res.json.Object("data").String("reason"), "expired")
+}
+
+func Test_Forbidden(t *testing.T) {
+	res := read(Forbidden(map[string]any{"role": "reader"}))
+	assert.Equal(t, res.status, 403)
+	assert.Equal(t, res.body, `{"code":2006,"error":"forbidden","data":{"role":"reader"}}`)
+	assert.Equal(t, res.log["res"], "58")
+	assert.Equal(t, res.log["code"], "2006")
+	assert.Equal(t, res.log["status"], "403")
+}
+
+func Test_MethodNotAllowed(t *testing.T) {
+	res := read(MethodNotAllowed([]string{"GET", "POST"}, nil))
+	assert.Equal(t, res.status, 405)
+	assert.Equal(t, res.headers["Allow"], "GET, POST")
+	assert.Equal(t, res.json.Int("code"), 2010)
+	assert.Equal(t, res.json.String("error"), "method not allowed")
+	assert.Equal(t, res.log["code"], "2010")
+	assert.Equal(t, res.log["status"], "405")
+}
+
+func Test_Conflict(t *testing.T) {
+	res := read(Conflict(map[string]any{"field": "email"}))
+	assert.Equal(t, res.status, 409)
+	assert.Equal(t, res.json.Int("code"), 2007)
+	assert.Equal(t, res.json.String("error"), "conflict")
+	assert.Equal(t, res.json.Object("data").String("field"), "email")
+	assert.Equal(t, res.log["code"], "2007")
+	assert.Equal(t, res.log["status"], "409")
+}
+
+func Test_PayloadTooLarge(t *testing.T) {
+	res := read(PayloadTooLarge(map[string]any{"max": 1024}))
+	assert.Equal(t, res.status, 413)
+	assert.Equal(t, res.json.Int("code"), 2009)
+	assert.Equal(t, res.json.String("error"), "payload too large")
+	assert.Equal(t, res.json.Object("data").Int("max"), 1024)
+	assert.Equal(t, res.log["code"], "2009")
+	assert.Equal(t, res.log["status"], "413")
+}
+
+func Test_TooManyRequests(t *testing.T) {
+	res := read(TooManyRequests(1500*time.Millisecond, nil))
+	assert.Equal(t, res.status, 429)
+	assert.Equal(t, res.headers["Retry-After"], "2")
+	assert.Equal(t, res.json.Int("code"), 2008)
+	assert.Equal(t, res.json.String("error"), "too many requests")
+	assert.Equal(t, res.log["code"], "2008")
+	assert.Equal(t, res.log["status"], "429")
+
+	// never less than a second
+	res = read(TooManyRequests(0, nil))
+	assert.Equal(t, res.headers["Retry-After"], "1")
+}
+
+func Test_DynamicError_InvalidData(t *testing.T) {
+	res := read(Conflict(make(chan bool)))
+	assert.Equal(t, res.status, 409)
+	assert.Equal(t, res.body, `{"code":2007,"error":"conflict"}`)
+}
+
+func Test_Created(t *testing.T) {
+	res := read(Created(map[string]any{"id": 9}, "/v1/things/9"))
+	assert.Equal(t, res.status, 201)
+	assert.Equal(t, res.body, `{"id":9}`)
+	assert.Equal(t, res.headers["Location"], "/v1/things/9")
+	assert.Equal(t, res.log["res"], "8")
+	assert.Equal(t, res.log["status"], "201")
+}
+
+func Test_Created_InvalidBody(t *testing.T) {
+	res := read(Created(make(chan bool), "/v1/things/9"))
+	assert.Equal(t, res.status, 500)
+	assert.Equal(t, res.json.Int("code"), 2002)
+	assert.Equal(t, res.headers["Location"], "")
+}
+
+func Test_NoContent(t *testing.T) {
+	res := read(NoContent())
+	assert.Equal(t, res.status, 204)
+	assert.Equal(t, res.body, "")
+	assert.Equal(t, res.log["res"], "0")
+	assert.Equal(t, res.log["status"], "204")
+}
+
+func Test_Redirect(t *testing.T) {
+	res := read(Redirect("https://www.sqlkite.com/"))
+	assert.Equal(t, res.status, 302)
+	assert.Equal(t, res.headers["Location"], "https://www.sqlkite.com/")
+	assert.Equal(t, res.log["status"], "302")
+
+	res = read(PermanentRedirect("/v2/"))
+	assert.Equal(t, res.status, 308)
+	assert.Equal(t, res.headers["Location"], "/v2/")
+	assert.Equal(t, res.log["status"], "308")
+}
+
+func Test_DynamicResponse_Headers(t *testing.T) {
+	cookie := new(fasthttp.Cookie)
+	cookie.SetKey("sid")
+	cookie.SetValue("abc123")
+	cookie.SetHTTPOnly(true)
+
+	base := OkBytes([]byte(`{"hi":1}`))
+	res := read(base.
+		Header("Cache-Control", "no-store").
+		Header("X-Power", "9001").
+		Cookie(cookie))
+
+	assert.Equal(t, res.status, 200)
+	assert.Equal(t, res.json.Int("hi"), 1)
+	assert.Equal(t, res.headers["Cache-Control"], "no-store")
+	assert.Equal(t, res.headers["X-Power"], "9001")
+	assert.Equal(t, res.headers["Set-Cookie"], "sid=abc123; HttpOnly")
+
+	// original is untouched
+	res = read(base)
+	assert.Equal(t, res.headers["Cache-Control"], "")
+}
+
+func Test_StaticResponse_Headers(t *testing.T) {
+	base := StaticNotFound(1023)
+	withHeader := base.Header("Cache-Control", "max-age=60")
+	other := base.Header("X-Other", "1")
+
+	res := read(withHeader)
+	assert.Equal(t, res.status, 404)
+	assert.Equal(t, res.body, `{"code":1023,"error":"not found"}`)
+	assert.Equal(t, res.headers["Cache-Control"], "max-age=60")
+	assert.Equal(t, res.headers["X-Other"], "")
+
+	res = read(other)
+	assert.Equal(t, res.headers["Cache-Control"], "")
+	assert.Equal(t, res.headers["X-Other"], "1")
+
+	res = read(base)
+	assert.Equal(t, res.headers["Cache-Control"], "")
+	assert.Equal(t, res.headers["X-Other"], "")
+}
+
+func Test_ErrorIdResponse_Headers(t *testing.T) {
+	res := read(ServerError().Header("Retry-After", "5").Cookie(testCookie("a", "b")))
+	assert.Equal(t, res.status, 500)
+	assert.Equal(t, res.headers["Retry-After"], "5")
+	assert.Equal(t, res.headers["Set-Cookie"], "a=b")
+	assert.Equal(t, res.headers["Error-Id"], res.json.String("error_id"))
+}
+
+func Test_Ok_Created_Headers(t *testing.T) {
+	res := read(Ok(map[string]int{"a": 1}).Header("X-Power", "9001").Cookie(testCookie("a", "b")))
+	assert.Equal(t, res.status, 200)
+	assert.Equal(t, res.headers["X-Power"], "9001")
+	assert.Equal(t, res.headers["Set-Cookie"], "a=b")
+
+	res = read(Created(nil, "/users/1").Header("X-Power", "9001"))
+	assert.Equal(t, res.status, 201)
+	assert.Equal(t, res.headers["Location"], "/users/1")
+	assert.Equal(t, res.headers["X-Power"], "9001")
+
+	// serialization errors keep their Error-Id and take headers
+	res = read(Ok(make(chan bool)).Header("X-Power", "9001"))
+	assert.Equal(t, res.status, 500)
+	assert.Equal(t, res.headers["X-Power"], "9001")
+	assert.Equal(t, res.headers["Error-Id"], res.json.String("error_id"))
+	assert.Equal(t, res.log["eid"], res.json.String("error_id"))
+
+	res = read(Created(make(chan bool), "/users/1"))
+	assert.Equal(t, res.status, 500)
+	assert.Equal(t, res.json.Int("code"), 2002)
+	assert.Equal(t, res.headers["Location"], "")
+}
+
+func testCookie(name string, value string) *fasthttp.Cookie {
+	cookie := fasthttp.AcquireCookie()
+	cookie.SetKey(name)
+	cookie.SetValue(value)
+	return cookie
+}
+
 func read(res Response) TestResponse {
 	conn := &fasthttp.RequestCtx{}
 	res.Write(conn)
 
 	body := conn.Response.Body()
+	headers := make(map[string]string)
+	conn.Response.Header.VisitAll(func(key []byte, value []byte) {
+		headers[string(key)] = string(value)
+	})
+
 	var json typed.Typed
 	if len(body) > 0 {
 		json = typed.Must(body)
@@ -138,9 +343,10 @@ func read(res Response) TestResponse {
 	res.EnhanceLog(logger)
 
 	return TestResponse{
-		json:   json,
-		body:   string(body),
-		status: conn.Response.StatusCode(),
-		log:    log.KvParse(string(logger.Bytes())),
+		json:    json,
+		body:    string(body),
+		headers: headers,
+		status:  conn.Response.StatusCode(),
+		log:     log.KvParse(string(logger.Bytes())),
 	}
 }
@@ -0,0 +1,203 @@
+package http
+
+/*
+Wraps a fasthttp.Server with the lifecycle every one of our services
+needs: listen, wait for SIGINT/SIGTERM (or an explicit Shutdown),
+give in-flight requests a drain period to complete, and then run
+shutdown hooks (closing pg.DB, flushing logs, ...).
+
+Hooks run in the order that they were registered, and every hook
+runs even if an earlier one fails.
+*/
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/log"
+)
+
+type ServerConfig struct {
+	Address string `json:"address"`
+
+	// in seconds
+	ReadTimeout  uint16 `json:"read_timeout"`
+	WriteTimeout uint16 `json:"write_timeout"`
+	IdleTimeout  uint16 `json:"idle_timeout"`
+	DrainTimeout uint16 `json:"drain_timeout"`
+
+	MaxBodySize uint32 `json:"max_body_size"`
+	Concurrency uint32 `json:"concurrency"`
+}
+
+type shutdownHook struct {
+	name string
+	fn   func() error
+}
+
+type Server struct {
+	address string
+	drain   time.Duration
+	fast    *fasthttp.Server
+	hooks   []shutdownHook
+	stop    chan struct{}
+	once    sync.Once
+}
+
+func NewServer(config ServerConfig, handler fasthttp.RequestHandler) *Server {
+	address := config.Address
+	if address == "" {
+		address = "127.0.0.1:5200"
+	}
+
+	readTimeout := config.ReadTimeout
+	if readTimeout == 0 {
+		readTimeout = 10
+	}
+
+	writeTimeout := config.WriteTimeout
+	if writeTimeout == 0 {
+		writeTimeout = 10
+	}
+
+	idleTimeout := config.IdleTimeout
+	if idleTimeout == 0 {
+		idleTimeout = 60
+	}
+
+	drainTimeout := config.DrainTimeout
+	if drainTimeout == 0 {
+		drainTimeout = 10
+	}
+
+	maxBodySize := config.MaxBodySize
+	if maxBodySize == 0 {
+		maxBodySize = fasthttp.DefaultMaxRequestBodySize
+	}
+
+	return &Server{
+		address: address,
+		stop:    make(chan struct{}),
+		drain:   time.Duration(drainTimeout) * time.Second,
+		fast: &fasthttp.Server{
+			Handler:            handler,
+			Name:               "sqlkite",
+			Logger:             serverLogger{},
+			CloseOnShutdown:    true,
+			Concurrency:        int(config.Concurrency),
+			MaxRequestBodySize: int(maxBodySize),
+			ReadTimeout:        time.Duration(readTimeout) * time.Second,
+			WriteTimeout:       time.Duration(writeTimeout) * time.Second,
+			IdleTimeout:        time.Duration(idleTimeout) * time.Second,
+		},
+	}
+}
+
+// Register a function to be called once the server has stopped
+// accepting requests and in-flight requests have been drained.
+func (s *Server) OnShutdown(name string, fn func() error) *Server {
+	s.hooks = append(s.hooks, shutdownHook{name: name, fn: fn})
+	return s
+}
+
+// Listens on the configured address and blocks until the server
+// is shutdown (via a signal or a call to Shutdown).
+func (s *Server) Listen() error {
+	ln, err := net.Listen("tcp", s.address)
+	if err != nil {
+		return log.Err(utils.ERR_HTTP_LISTEN, err).String("address", s.address)
+	}
+	return s.Serve(ln)
+}
+
+// Like Listen, but with a listener provided by the caller
+func (s *Server) Serve(ln net.Listener) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.fast.Serve(ln)
+	}()
+
+	log.Info("server_start").
+		String("address", ln.Addr().String()).
+		Log()
+
+	select {
+	case err := <-served:
+		// the server stopped without being asked to, we still want
+		// to run our hooks so that resources are cleaned up
+		s.runHooks()
+		if err == nil {
+			return nil
+		}
+		return log.Err(utils.ERR_HTTP_SERVE, err)
+	case sig := <-signals:
+		log.Info("server_signal").String("signal", sig.String()).Log()
+	case <-s.stop:
+	}
+
+	return s.shutdown()
+}
+
+// Programmatically stops the server, as though it had received a SIGTERM.
+// Safe to call multiple times.
+func (s *Server) Shutdown() {
+	s.once.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *Server) shutdown() error {
+	log.Info("server_shutdown").
+		Int64("drain_ms", s.drain.Milliseconds()).
+		Log()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.drain)
+	defer cancel()
+
+	var shutdownErr error
+	if err := s.fast.ShutdownWithContext(ctx); err != nil {
+		shutdownErr = log.Err(utils.ERR_HTTP_SHUTDOWN, err).String("step", "drain")
+		log.Error("server_drain").Err(shutdownErr).Log()
+	}
+
+	if err := s.runHooks(); err != nil && shutdownErr == nil {
+		shutdownErr = err
+	}
+
+	log.Info("server_stopped").Log()
+	return shutdownErr
+}
+
+// runs every hook, returning the first error
+func (s *Server) runHooks() error {
+	var hooksErr error
+	for _, hook := range s.hooks {
+		if err := hook.fn(); err != nil {
+			err := log.Err(utils.ERR_HTTP_SHUTDOWN, err).String("step", hook.name)
+			log.Error("server_shutdown_hook").Err(err).Log()
+			if hooksErr == nil {
+				hooksErr = err
+			}
+		}
+	}
+	return hooksErr
+}
+
+// fasthttp.Logger which writes to our logs
+type serverLogger struct{}
+
+func (_ serverLogger) Printf(format string, args ...any) {
+	log.Error("fasthttp").String("err", fmt.Sprintf(format, args...)).Log()
+}
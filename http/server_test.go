@@ -0,0 +1,161 @@
+package http
+
+import (
+	"errors"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/log"
+)
+
+func Test_Server_Defaults(t *testing.T) {
+	s := NewServer(ServerConfig{}, nil)
+	assert.Equal(t, s.address, "127.0.0.1:5200")
+	assert.Equal(t, s.drain, 10*time.Second)
+	assert.Equal(t, s.fast.ReadTimeout, 10*time.Second)
+	assert.Equal(t, s.fast.WriteTimeout, 10*time.Second)
+	assert.Equal(t, s.fast.IdleTimeout, 60*time.Second)
+	assert.Equal(t, s.fast.MaxRequestBodySize, fasthttp.DefaultMaxRequestBodySize)
+	assert.Equal(t, s.fast.Concurrency, 0)
+}
+
+func Test_Server_Config(t *testing.T) {
+	s := NewServer(ServerConfig{
+		Address:      "127.0.0.1:9001",
+		ReadTimeout:  1,
+		WriteTimeout: 2,
+		IdleTimeout:  3,
+		DrainTimeout: 4,
+		MaxBodySize:  1024,
+		Concurrency:  5,
+	}, nil)
+	assert.Equal(t, s.address, "127.0.0.1:9001")
+	assert.Equal(t, s.drain, 4*time.Second)
+	assert.Equal(t, s.fast.ReadTimeout, time.Second)
+	assert.Equal(t, s.fast.WriteTimeout, 2*time.Second)
+	assert.Equal(t, s.fast.IdleTimeout, 3*time.Second)
+	assert.Equal(t, s.fast.MaxRequestBodySize, 1024)
+	assert.Equal(t, s.fast.Concurrency, 5)
+}
+
+func Test_Server_Listen_Error(t *testing.T) {
+	s := NewServer(ServerConfig{Address: "nope"}, nil)
+	err := s.Listen()
+	assert.Equal(t, err.(*log.StructuredError).Code, 3005)
+}
+
+func Test_Server_Shutdown(t *testing.T) {
+	var hooks []string
+	s := NewServer(ServerConfig{DrainTimeout: 1}, func(conn *fasthttp.RequestCtx) {
+		conn.SetBodyString("ok")
+	}).
+		OnShutdown("first", func() error {
+			hooks = append(hooks, "first")
+			return nil
+		}).
+		OnShutdown("second", func() error {
+			hooks = append(hooks, "second")
+			return nil
+		})
+
+	var err error
+	logged := tests.CaptureLog(func() {
+		err = serveAndStop(t, s, func() { s.Shutdown() })
+	})
+	assert.Nil(t, err)
+	assert.List(t, hooks, []string{"first", "second"})
+
+	logs := log.KvParseAll(logged)
+	assert.Equal(t, logs[0]["c"], "server_start")
+	assert.Equal(t, logs[1]["c"], "server_shutdown")
+	assert.Equal(t, logs[1]["drain_ms"], "1000")
+	assert.Equal(t, logs[2]["c"], "server_stopped")
+}
+
+func Test_Server_Signal(t *testing.T) {
+	ran := false
+	s := NewServer(ServerConfig{}, func(conn *fasthttp.RequestCtx) {}).
+		OnShutdown("hook", func() error {
+			ran = true
+			return nil
+		})
+
+	var err error
+	logged := tests.CaptureLog(func() {
+		err = serveAndStop(t, s, func() {
+			syscall.Kill(os.Getpid(), syscall.SIGTERM)
+		})
+	})
+	assert.Nil(t, err)
+	assert.True(t, ran)
+
+	logs := log.KvParseAll(logged)
+	assert.Equal(t, logs[1]["c"], "server_signal")
+	assert.Equal(t, logs[1]["signal"], "terminated")
+}
+
+func Test_Server_FailingHook(t *testing.T) {
+	ran := false
+	s := NewServer(ServerConfig{}, func(conn *fasthttp.RequestCtx) {}).
+		OnShutdown("pg", func() error {
+			return errors.New("pg close failed")
+		}).
+		OnShutdown("log", func() error {
+			ran = true
+			return nil
+		})
+
+	var err error
+	logged := tests.CaptureLog(func() {
+		err = serveAndStop(t, s, func() { s.Shutdown() })
+	})
+
+	// subsequent hooks still run
+	assert.True(t, ran)
+
+	se := err.(*log.StructuredError)
+	assert.Equal(t, se.Code, 3007)
+	assert.Equal(t, se.Data["step"].(string), "pg")
+
+	logs := log.KvParseAll(logged)
+	assert.Equal(t, logs[2]["c"], "server_shutdown_hook")
+	assert.Equal(t, logs[2]["err"], `"pg close failed"`)
+	assert.Equal(t, logs[2]["step"], "pg")
+}
+
+// starts the server, makes sure it responds to a request, calls stop
+// and waits for the server to stop
+func serveAndStop(t *testing.T, s *Server, stop func()) error {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Serve(ln)
+	}()
+
+	req := fasthttp.AcquireRequest()
+	res := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(res)
+
+	req.SetRequestURI("http://" + ln.Addr().String() + "/")
+	assert.Nil(t, fasthttp.DoTimeout(req, res, time.Second))
+	assert.Equal(t, res.StatusCode(), 200)
+
+	stop()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(5 * time.Second):
+		assert.Fail(t, "server did not stop")
+		return nil
+	}
+}
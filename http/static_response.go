@@ -13,7 +13,16 @@ import (
 )
 
 var (
-	InvalidJSON = StaticError(400, utils.RES_INVALID_JSON_PAYLOAD, "invalid json payload")
+	InvalidJSON    = StaticError(400, utils.RES_INVALID_JSON_PAYLOAD, "invalid json payload")
+	RequestTimeout = StaticError(503, utils.RES_TIMEOUT, "request timed out")
+
+	noContent = StaticResponse{
+		status: 204,
+		logData: log.NewField().
+			Int("status", 204).
+			Int("res", 0).
+			Finalize(),
+	}
 )
 
 // We know the status/body/logData upfront (lets us optimize
@@ -21,14 +30,27 @@ var (
 type StaticResponse struct {
 	status  int
 	body    []byte
+	headers []header
 	logData log.Field
 }
 
 func (r StaticResponse) Write(conn *fasthttp.RequestCtx) {
 	conn.SetStatusCode(r.status)
+	writeHeaders(conn, r.headers)
 	conn.SetBody(r.body)
 }
 
+// Static responses are typically shared, so this returns a copy
+// and leaves r untouched
+func (r StaticResponse) Header(key string, value string) StaticResponse {
+	r.headers = withHeader(r.headers, key, value)
+	return r
+}
+
+func (r StaticResponse) Cookie(cookie *fasthttp.Cookie) StaticResponse {
+	return r.Header("Set-Cookie", cookie.String())
+}
+
 func (r StaticResponse) EnhanceLog(logger log.Logger) log.Logger {
 	logger.Field(r.logData)
 	return logger
@@ -64,3 +86,7 @@ func StaticError(status int, code int, error string) StaticResponse {
 func StaticNotFound(code int) StaticResponse {
 	return StaticError(404, code, "not found")
 }
+
+func NoContent() StaticResponse {
+	return noContent
+}
@@ -0,0 +1,243 @@
+package password
+
+/*
+Hashes passwords with argon2id, encoded in the PHC string format:
+
+	$argon2id$v=19$m=65536,t=3,p=2$<salt>$<hash>
+
+The salt and hash are unpadded standard base64. Because the parameters
+are part of the encoded hash, they can be changed (via Configure)
+without invalidating existing hashes. Verify reports when a hash was
+created with different parameters (or is a legacy bcrypt hash) so that
+the caller can re-hash the password, which it only has in plain text
+on a successful login.
+*/
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/log"
+)
+
+var (
+	ErrInvalidHash         = errors.New("password: invalid hash")
+	ErrIncompatibleVersion = errors.New("password: incompatible argon2 version")
+
+	b64 = base64.RawStdEncoding
+
+	defaults = params{
+		memory:      64 * 1024,
+		iterations:  3,
+		parallelism: 2,
+		saltLength:  16,
+		keyLength:   32,
+	}
+
+	current = defaults
+)
+
+// Hashes with parameters more than this many times the current ones (or
+// the defaults, if larger) are rejected as ErrInvalidHash rather than
+// verified, so that a corrupt or malicious hash (say m=4294967295) can't
+// make Verify allocate gigabytes of memory or run for minutes.
+const maxParamsFactor = 4
+
+type Config struct {
+	// in KiB
+	Memory      uint32 `json:"memory"`
+	Iterations  uint32 `json:"iterations"`
+	Parallelism uint8  `json:"parallelism"`
+	SaltLength  uint32 `json:"salt_length"`
+	KeyLength   uint32 `json:"key_length"`
+}
+
+type params struct {
+	memory      uint32
+	iterations  uint32
+	parallelism uint8
+	saltLength  uint32
+	keyLength   uint32
+}
+
+func Configure(config Config) error {
+	p := params{
+		memory:      config.Memory,
+		iterations:  config.Iterations,
+		parallelism: config.Parallelism,
+		saltLength:  config.SaltLength,
+		keyLength:   config.KeyLength,
+	}
+
+	if p.memory == 0 {
+		p.memory = defaults.memory
+	}
+	if p.iterations == 0 {
+		p.iterations = defaults.iterations
+	}
+	if p.parallelism == 0 {
+		p.parallelism = defaults.parallelism
+	}
+	if p.saltLength == 0 {
+		p.saltLength = defaults.saltLength
+	}
+	if p.keyLength == 0 {
+		p.keyLength = defaults.keyLength
+	}
+
+	if p.memory < 8*uint32(p.parallelism) {
+		return log.Errf(utils.ERR_PASSWORD_CONFIG, "password.memory must be at least 8 * parallelism")
+	}
+	if p.saltLength < 8 || p.keyLength < 16 {
+		return log.Errf(utils.ERR_PASSWORD_CONFIG, "password.salt_length must be at least 8 and password.key_length at least 16")
+	}
+
+	current = p
+	log.Info("password_config").
+		Int("memory", int(p.memory)).
+		Int("iterations", int(p.iterations)).
+		Int("parallelism", int(p.parallelism)).
+		Log()
+
+	return nil
+}
+
+func Hash(password string) (string, error) {
+	p := current
+	salt := make([]byte, p.saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey(utils.S2B(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.iterations, p.parallelism,
+		b64.EncodeToString(salt), b64.EncodeToString(key)), nil
+}
+
+// Returns whether the password matches the hash and, when it does,
+// whether the password should be re-hashed (with Hash) and stored
+// because the hash uses outdated parameters or algorithm. A
+// non-matching password isn't an error.
+func Verify(password string, hash string) (bool, bool, error) {
+	if isBcrypt(hash) {
+		err := bcrypt.CompareHashAndPassword(utils.S2B(hash), utils.S2B(password))
+		if err == nil {
+			return true, true, nil
+		}
+		if err == bcrypt.ErrMismatchedHashAndPassword {
+			return false, false, nil
+		}
+		return false, false, ErrInvalidHash
+	}
+
+	p, salt, key, err := decode(hash)
+	if err != nil {
+		return false, false, err
+	}
+
+	actual := argon2.IDKey(utils.S2B(password), salt, p.iterations, p.memory, p.parallelism, p.keyLength)
+	if subtle.ConstantTimeCompare(actual, key) != 1 {
+		return false, false, nil
+	}
+	return true, p != current, nil
+}
+
+// Compares two secrets (e.g. API keys) in constant time. The time taken
+// still depends on the length of the inputs.
+func Equal(a string, b string) bool {
+	return subtle.ConstantTimeCompare(utils.S2B(a), utils.S2B(b)) == 1
+}
+
+func isBcrypt(hash string) bool {
+	return strings.HasPrefix(hash, "$2a$") || strings.HasPrefix(hash, "$2b$") || strings.HasPrefix(hash, "$2y$")
+}
+
+func decode(hash string) (params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != "argon2id" {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+
+	version, ok := parseParam(parts[2], "v=", 32)
+	if !ok {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+	if version != argon2.Version {
+		return params{}, nil, nil, ErrIncompatibleVersion
+	}
+
+	settings := strings.Split(parts[3], ",")
+	if len(settings) != 3 {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+	memory, ok1 := parseParam(settings[0], "m=", 32)
+	iterations, ok2 := parseParam(settings[1], "t=", 32)
+	parallelism, ok3 := parseParam(settings[2], "p=", 8)
+	if !ok1 || !ok2 || !ok3 || memory == 0 || iterations == 0 || parallelism == 0 {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+	p := params{
+		memory:      uint32(memory),
+		iterations:  uint32(iterations),
+		parallelism: uint8(parallelism),
+	}
+
+	if exceedsLimit(uint64(p.memory), current.memory, defaults.memory) ||
+		exceedsLimit(uint64(p.iterations), current.iterations, defaults.iterations) ||
+		exceedsLimit(uint64(p.parallelism), uint32(current.parallelism), uint32(defaults.parallelism)) {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+
+	// checked before decoding, so a huge salt or key is never decoded
+	// (base64 is 4 characters per 3 bytes)
+	if exceedsLimit(uint64(len(parts[4]))*3/4, current.saltLength, defaults.saltLength) ||
+		exceedsLimit(uint64(len(parts[5]))*3/4, current.keyLength, defaults.keyLength) {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+
+	salt, err := b64.DecodeString(parts[4])
+	if err != nil || len(salt) == 0 {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+	key, err := b64.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return params{}, nil, nil, ErrInvalidHash
+	}
+
+	p.saltLength = uint32(len(salt))
+	p.keyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// Parses a "name=value" segment of the hash. Unlike fmt.Sscanf, trailing
+// garbage, signs and leading zeros are rejected, so that only the hashes
+// we generate (or identical ones) are accepted.
+func parseParam(segment string, prefix string, bitSize int) (uint64, bool) {
+	if !strings.HasPrefix(segment, prefix) {
+		return 0, false
+	}
+	value := segment[len(prefix):]
+	n, err := strconv.ParseUint(value, 10, bitSize)
+	if err != nil || strconv.FormatUint(n, 10) != value {
+		return 0, false
+	}
+	return n, true
+}
+
+func exceedsLimit(value uint64, current uint32, dflt uint32) bool {
+	limit := current
+	if dflt > limit {
+		limit = dflt
+	}
+	return value > uint64(limit)*maxParamsFactor
+}
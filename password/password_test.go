@@ -0,0 +1,169 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+	"src.sqlkite.com/tests"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/log"
+)
+
+func init() {
+	// keep tests fast
+	tests.CaptureLog(func() {
+		if err := Configure(Config{Memory: 1024, Iterations: 1, Parallelism: 1}); err != nil {
+			panic(err)
+		}
+	})
+}
+
+func Test_Configure_Invalid(t *testing.T) {
+	defer configure(t, Config{Memory: 1024, Iterations: 1, Parallelism: 1})
+
+	err := Configure(Config{Memory: 8, Parallelism: 4})
+	assert.Equal(t, err.(*log.StructuredError).Code, 3010)
+
+	err = Configure(Config{SaltLength: 4})
+	assert.Equal(t, err.(*log.StructuredError).Code, 3010)
+}
+
+func Test_Hash_Format(t *testing.T) {
+	hash, err := Hash("it's over 9000")
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	parts := strings.Split(hash, "$")
+	assert.Equal(t, len(parts), 6)
+	assert.Equal(t, len(parts[4]), 22) // 16 byte salt
+	assert.Equal(t, len(parts[5]), 43) // 32 byte key
+
+	// salted
+	other, _ := Hash("it's over 9000")
+	assert.NotEqual(t, hash, other)
+}
+
+func Test_Verify_Argon2id(t *testing.T) {
+	hash, _ := Hash("it's over 9000")
+
+	match, rehash, err := Verify("it's over 9000", hash)
+	assert.Nil(t, err)
+	assert.True(t, match)
+	assert.False(t, rehash)
+
+	match, rehash, err = Verify("it's over 9001", hash)
+	assert.Nil(t, err)
+	assert.False(t, match)
+	assert.False(t, rehash)
+}
+
+func Test_Verify_KnownHash(t *testing.T) {
+	// a fixed hash, with a shorter key and different parameters than
+	// we're configured with, to catch changes in how hashes are decoded
+	hash := "$argon2id$v=19$m=65536,t=2,p=4$c29tZXNhbHQ$F1jG2CV3/Nr+yRuIsPKw0J9r4s7cJHBU"
+	match, rehash, err := Verify("password", hash)
+	assert.Nil(t, err)
+	assert.True(t, match)
+	assert.True(t, rehash)
+}
+
+func Test_Verify_NeedsRehash(t *testing.T) {
+	hash, _ := Hash("it's over 9000")
+
+	configure(t, Config{Memory: 2048, Iterations: 1, Parallelism: 1})
+	defer configure(t, Config{Memory: 1024, Iterations: 1, Parallelism: 1})
+
+	match, rehash, err := Verify("it's over 9000", hash)
+	assert.Nil(t, err)
+	assert.True(t, match)
+	assert.True(t, rehash)
+
+	// no rehash signal on a mismatch
+	match, rehash, _ = Verify("nope", hash)
+	assert.False(t, match)
+	assert.False(t, rehash)
+
+	hash, _ = Hash("it's over 9000")
+	_, rehash, _ = Verify("it's over 9000", hash)
+	assert.False(t, rehash)
+}
+
+func Test_Verify_Bcrypt(t *testing.T) {
+	legacy, _ := bcrypt.GenerateFromPassword([]byte("it's over 9000"), bcrypt.MinCost)
+
+	match, rehash, err := Verify("it's over 9000", string(legacy))
+	assert.Nil(t, err)
+	assert.True(t, match)
+	assert.True(t, rehash)
+
+	match, rehash, err = Verify("nope", string(legacy))
+	assert.Nil(t, err)
+	assert.False(t, match)
+	assert.False(t, rehash)
+
+	_, _, err = Verify("nope", "$2a$10$short")
+	assert.True(t, err == ErrInvalidHash)
+}
+
+func Test_Verify_InvalidHash(t *testing.T) {
+	for _, hash := range []string{
+		"",
+		"plain",
+		"$argon2i$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=0,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=1$!!$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$",
+		"$argon2id$x$m=1024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19x$m=1024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=+19$m=1024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=1junk$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=1,x=2$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=01024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m= 1024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$t=1,m=1024,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=256$c29tZXNhbHQ$RdescudvJCsgt3ub",
+	} {
+		_, _, err := Verify("password", hash)
+		assert.True(t, err == ErrInvalidHash)
+	}
+
+	_, _, err := Verify("password", "$argon2id$v=16$m=1024,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub")
+	assert.True(t, err == ErrIncompatibleVersion)
+}
+
+func Test_Verify_ExcessiveParams(t *testing.T) {
+	for _, hash := range []string{
+		"$argon2id$v=19$m=4294967295,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=262145,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=4294967295,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=13,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=9$c29tZXNhbHQ$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=1$" + strings.Repeat("A", 100) + "$RdescudvJCsgt3ub",
+		"$argon2id$v=19$m=1024,t=1,p=1$c29tZXNhbHQ$" + strings.Repeat("A", 200),
+	} {
+		_, _, err := Verify("password", hash)
+		assert.True(t, err == ErrInvalidHash)
+	}
+
+	// the limit follows the configuration when it's above the defaults
+	configure(t, Config{Memory: 128 * 1024, Iterations: 1, Parallelism: 1})
+	defer configure(t, Config{Memory: 1024, Iterations: 1, Parallelism: 1})
+	_, _, err := Verify("password", "$argon2id$v=19$m=524289,t=1,p=1$c29tZXNhbHQ$RdescudvJCsgt3ub")
+	assert.True(t, err == ErrInvalidHash)
+}
+
+func Test_Equal(t *testing.T) {
+	assert.True(t, Equal("", ""))
+	assert.True(t, Equal("abc", "abc"))
+	assert.False(t, Equal("abc", "abd"))
+	assert.False(t, Equal("abc", "abcd"))
+}
+
+func configure(t *testing.T, config Config) {
+	t.Helper()
+	tests.CaptureLog(func() {
+		assert.Nil(t, Configure(config))
+	})
+}
@@ -24,6 +24,9 @@ type Row = pgx.Row
 
 type DB struct {
 	*pgxpool.Pool
+
+	// used by our helpers (Scalar, RowToMap, ...), see WithContext
+	ctx context.Context
 }
 
 func New(url string) (DB, error) {
@@ -31,11 +34,25 @@ func New(url string) (DB, error) {
 	if err != nil {
 		return DB{}, log.Err(utils.ERR_PG_INIT, err).String("url", url)
 	}
-	return DB{pool}, nil
+	return DB{Pool: pool}, nil
+}
+
+// Returns a DB whose helpers (Scalar, RowToMap, Transaction, ...) run
+// with the given context, so that they're cancelled when it is (e.g.
+// when an http request times out). The pool is shared.
+func (db DB) WithContext(ctx context.Context) DB {
+	return DB{Pool: db.Pool, ctx: ctx}
+}
+
+func (db DB) Context() context.Context {
+	if ctx := db.ctx; ctx != nil {
+		return ctx
+	}
+	return context.Background()
 }
 
 func Scalar[T any](db DB, sql string, args ...any) (T, error) {
-	row := db.Pool.QueryRow(context.Background(), sql, args...)
+	row := db.Pool.QueryRow(db.Context(), sql, args...)
 
 	var value T
 	err := row.Scan(&value)
@@ -60,18 +77,20 @@ func (db DB) TableExists(tableName string) (bool, error) {
 }
 
 func (db DB) Transaction(fn func(tx pgx.Tx) error) error {
-	bg := context.Background()
-	tx, err := db.Pool.Begin(bg)
+	ctx := db.Context()
+	tx, err := db.Pool.Begin(ctx)
 	if err != nil {
 		return err
 	}
 
-	defer tx.Rollback(bg)
+	// use a background context so that a cancelled ctx doesn't prevent
+	// the rollback from happening
+	defer tx.Rollback(context.Background())
 	if err := fn(tx); err != nil {
 		return err
 	}
 
-	return tx.Commit(bg)
+	return tx.Commit(ctx)
 }
 
 // Exists for our test factory which are designed to work with
@@ -124,13 +143,13 @@ func (_ DB) Placeholder(i int) string {
 }
 
 func (db DB) MustExec(sql string, args ...any) {
-	if _, err := db.Exec(context.Background(), sql, args...); err != nil {
+	if _, err := db.Exec(db.Context(), sql, args...); err != nil {
 		panic(err)
 	}
 }
 
 func (db DB) RowToMap(sql string, args ...any) (typed.Typed, error) {
-	rows, err := db.Query(context.Background(), sql, args...)
+	rows, err := db.Query(db.Context(), sql, args...)
 	if err != nil {
 		return typed.Typed{}, err
 	}
@@ -147,7 +166,7 @@ func (db DB) RowToMap(sql string, args ...any) (typed.Typed, error) {
 }
 
 func (db DB) RowsToMap(sql string, args ...any) ([]typed.Typed, error) {
-	rows, err := db.Query(context.Background(), sql, args...)
+	rows, err := db.Query(db.Context(), sql, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -46,6 +46,29 @@ func Test_Scalar(t *testing.T) {
 	assert.Equal(t, str, "hello")
 }
 
+func Test_DB_WithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cdb := db.WithContext(ctx)
+	assert.True(t, cdb.Context() == ctx)
+	assert.True(t, db.Context() == context.Background())
+
+	value, err := Scalar[int](cdb, "select 566")
+	assert.Nil(t, err)
+	assert.Equal(t, value, 566)
+
+	cancel()
+	_, err = Scalar[int](cdb, "select 566")
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	_, err = cdb.RowsToMap("select 1")
+	assert.True(t, errors.Is(err, context.Canceled))
+
+	// original db is unaffected
+	value, err = Scalar[int](db, "select 566")
+	assert.Nil(t, err)
+	assert.Equal(t, value, 566)
+}
+
 func Test_DB_TableExist(t *testing.T) {
 	db.MustExec("drop table if exists test_migrations")
 	exists, err := db.TableExists("test_migrations")
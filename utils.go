@@ -2,6 +2,7 @@ package utils
 
 import (
 	"encoding/binary"
+	"errors"
 	"reflect"
 	"unsafe"
 )
@@ -10,6 +11,10 @@ const (
 	reqIdEncoding = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
 )
 
+var (
+	ErrInvalidRequestId = errors.New("invalid request id")
+)
+
 func EncodeRequestId(requestId uint32, instanceId uint8) string {
 	var data [4]byte
 	id := data[:]
@@ -28,6 +33,35 @@ func EncodeRequestId(requestId uint32, instanceId uint8) string {
 	return string(encoded[:])
 }
 
+// The inverse of EncodeRequestId
+func DecodeRequestId(encoded string) (uint32, uint8, error) {
+	if len(encoded) != 8 {
+		return 0, 0, ErrInvalidRequestId
+	}
+
+	var v [8]byte
+	for i := 0; i < 8; i++ {
+		c := encoded[i]
+		switch {
+		case c >= 'A' && c <= 'Z':
+			v[i] = c - 'A'
+		case c >= '2' && c <= '7':
+			v[i] = c - '2' + 26
+		default:
+			return 0, 0, ErrInvalidRequestId
+		}
+	}
+
+	var id [4]byte
+	id[0] = v[1]>>2 | v[0]<<3
+	id[1] = v[3]>>4 | v[2]<<1 | (v[1]&0x3)<<6
+	id[2] = v[4]>>1 | (v[3]&0xF)<<4
+	id[3] = v[6]>>3 | v[5]<<2 | (v[4]&0x1)<<7
+	instanceId := v[7] | (v[6]&0x7)<<5
+
+	return binary.BigEndian.Uint32(id[:]), instanceId, nil
+}
+
 func S2B(s string) (b []byte) {
 	/* #nosec G103 */
 	bh := (*reflect.SliceHeader)(unsafe.Pointer(&b))
@@ -55,3 +55,21 @@ func Test_Paging(t *testing.T) {
 	assert.Equal(t, offset, 10)
 	assert.Equal(t, perpage, 10)
 }
+
+func Test_DecodeRequestId(t *testing.T) {
+	for _, requestId := range []uint32{0, 1, 31, 32, 255, 256, 9001, 1<<31 + 7, 4294967295} {
+		for _, instanceId := range []uint8{0, 1, 17, 31, 32, 200, 255} {
+			actualRequestId, actualInstanceId, err := DecodeRequestId(EncodeRequestId(requestId, instanceId))
+			assert.Nil(t, err)
+			assert.Equal(t, actualRequestId, requestId)
+			assert.Equal(t, actualInstanceId, instanceId)
+		}
+	}
+}
+
+func Test_DecodeRequestId_Invalid(t *testing.T) {
+	for _, encoded := range []string{"", "AAAAAAA", "AAAAAAAAA", "AAAAAAA1", "aAAAAAAA", "AAAA-AAA"} {
+		_, _, err := DecodeRequestId(encoded)
+		assert.True(t, err == ErrInvalidRequestId)
+	}
+}
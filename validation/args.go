@@ -0,0 +1,178 @@
+package validation
+
+/*
+Query strings are flat, so before handing them to the validators (via
+argsToTyped), we turn fasthttp.Args into a tree:
+
+	?ids=1&ids=2             => ids: ["1", "2"]
+	?ids[]=1&ids[]=2         => ids: ["1", "2"]
+	?ids[0]=1&ids[1]=2       => ids: {0: ["1"], 1: ["2"]}
+	?filter[name]=x          => filter: {name: ["x"]}
+	?users[0][name]=x        => users: {0: {name: ["x"]}}
+
+Each validator then picks what it needs. Scalars take the first value,
+arrays of scalars take every value (splitting each on commas, so
+?ids=1,2 also works, and including numeric keys, ordered by key) and
+arrays of objects take the objects with numeric keys, ordered by key.
+Arrays and maps given a plain value (?users=1) keep it, so that they
+report it as the wrong type rather than as missing.
+*/
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/typed"
+)
+
+type queryArgs struct {
+	values  map[string][][]byte
+	objects map[string]*queryArgs
+}
+
+func newQueryArgs(args *fasthttp.Args) *queryArgs {
+	root := &queryArgs{}
+	args.VisitAll(func(key []byte, value []byte) {
+		// fasthttp re-uses these buffers
+		root.add(string(key), append([]byte{}, value...))
+	})
+	return root
+}
+
+func (q *queryArgs) add(key string, value []byte) {
+	node := q
+	name := key
+
+	if open := strings.IndexByte(key, '['); open > 0 && key[len(key)-1] == ']' {
+		name = key[:open]
+		// "a][b][c" for a key of x[a][b][c]
+		for _, segment := range strings.Split(key[open+1:len(key)-1], "][") {
+			if segment == "" {
+				// x[] (or the meaningless x[][a]) is a repeated value of x
+				break
+			}
+			node = node.object(name)
+			name = segment
+		}
+	}
+
+	if node.values == nil {
+		node.values = make(map[string][][]byte)
+	}
+	node.values[name] = append(node.values[name], value)
+}
+
+func (q *queryArgs) object(name string) *queryArgs {
+	if q.objects == nil {
+		q.objects = make(map[string]*queryArgs)
+	}
+	o := q.objects[name]
+	if o == nil {
+		o = &queryArgs{}
+		q.objects[name] = o
+	}
+	return o
+}
+
+// The first value for the key, or nil if the key isn't present
+func (q *queryArgs) Peek(name string) []byte {
+	if values := q.values[name]; len(values) > 0 {
+		return values[0]
+	}
+	return nil
+}
+
+// Every value for the key, with comma-separated values split, followed by
+// the values of numeric keys (ids[0]=1&ids[1]=2), in key order. Returns
+// false if the key isn't present.
+func (q *queryArgs) PeekList(name string) ([][]byte, bool) {
+	values, exists := q.values[name]
+	if o := q.objects[name]; o != nil {
+		if indexed := o.indexedValues(); len(indexed) > 0 {
+			values = append(values[:len(values):len(values)], indexed...)
+			exists = true
+		}
+	}
+	if !exists {
+		return nil, false
+	}
+
+	list := make([][]byte, 0, len(values))
+	for _, value := range values {
+		if len(value) == 0 {
+			continue
+		}
+		for _, part := range strings.Split(utils.B2S(value), ",") {
+			list = append(list, []byte(part))
+		}
+	}
+	return list, true
+}
+
+// The tree as-is, with the first value of each key, for keys which no
+// validator will pick up
+func (q *queryArgs) raw() typed.Typed {
+	t := make(typed.Typed, len(q.values)+len(q.objects))
+	for key := range q.values {
+		t[key] = string(q.Peek(key))
+	}
+	for key, object := range q.objects {
+		t[key] = object.raw()
+	}
+	return t
+}
+
+func (q *queryArgs) Object(name string) *queryArgs {
+	return q.objects[name]
+}
+
+// The values with numeric keys, in key order
+func (q *queryArgs) indexedValues() [][]byte {
+	var values [][]byte
+	for _, key := range indexedKeys(q.values) {
+		values = append(values, q.values[key]...)
+	}
+	return values
+}
+
+// The nested objects with numeric keys, in key order
+func (q *queryArgs) Objects(name string) []*queryArgs {
+	o := q.objects[name]
+	if o == nil {
+		return nil
+	}
+
+	keys := indexedKeys(o.objects)
+	objects := make([]*queryArgs, len(keys))
+	for i, key := range keys {
+		objects[i] = o.objects[key]
+	}
+	return objects
+}
+
+// The keys of m which are non-negative integers, in numeric order
+func indexedKeys[T any](m map[string]T) []string {
+	type indexed struct {
+		index int
+		key   string
+	}
+
+	items := make([]indexed, 0, len(m))
+	for key := range m {
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 {
+			items = append(items, indexed{i, key})
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].index < items[j].index
+	})
+
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = item.key
+	}
+	return keys
+}
@@ -0,0 +1,146 @@
+package validation
+
+import (
+	"testing"
+
+	"github.com/valyala/fasthttp"
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/typed"
+)
+
+func Test_QueryArgs_Parse(t *testing.T) {
+	args := new(fasthttp.Args)
+	args.Parse("a=1&a=2&b[]=3&b[]=4&c[d]=5&c[e][f]=6&g[0][h]=7&bad[=8&[x]=9&empty=")
+	q := newQueryArgs(args)
+
+	assert.Equal(t, string(q.Peek("a")), "1")
+	assert.Equal(t, string(q.Peek("b")), "3")
+	assert.Equal(t, string(q.Object("c").Peek("d")), "5")
+	assert.Equal(t, string(q.Object("c").Object("e").Peek("f")), "6")
+	assert.Equal(t, string(q.Objects("g")[0].Peek("h")), "7")
+	assert.Equal(t, string(q.Peek("bad[")), "8")
+	assert.Equal(t, string(q.Peek("[x]")), "9")
+	assert.NotNil(t, q.Peek("empty"))
+	assert.Equal(t, len(q.Peek("empty")), 0)
+	assert.Nil(t, q.Peek("nope"))
+	assert.Nil(t, q.Object("nope"))
+
+	list, exists := q.PeekList("a")
+	assert.True(t, exists)
+	assert.Equal(t, len(list), 2)
+
+	list, exists = q.PeekList("empty")
+	assert.True(t, exists)
+	assert.Equal(t, len(list), 0)
+
+	_, exists = q.PeekList("nope")
+	assert.False(t, exists)
+
+	// numeric keys are list entries, others aren't
+	q = newQueryArgs(parseArgs("ids[1]=b&ids[0]=a&ids[x]=c&ids[-1]=d"))
+	list, exists = q.PeekList("ids")
+	assert.True(t, exists)
+	assert.Equal(t, len(list), 2)
+	assert.Equal(t, string(list[0]), "a")
+	assert.Equal(t, string(list[1]), "b")
+
+	_, exists = newQueryArgs(parseArgs("ids[x]=c")).PeekList("ids")
+	assert.False(t, exists)
+}
+
+func Test_Args_ScalarArray(t *testing.T) {
+	o := Object().
+		Field("ids", ScalarArray().Required().Unique().Validator(Int().Min(1))).
+		Field("tags", ScalarArray())
+
+	for _, qs := range []string{"ids=1&ids=2&ids=3", "ids[]=1&ids[]=2&ids[]=3", "ids=1,2&ids=3", "ids=1,2,3", "ids[1]=2&ids[0]=1&ids[2]=3", "ids[0]=1,2&ids[10]=3"} {
+		input, res := testQueryString(o, qs)
+		assert.Validation(t, res).FieldsHaveNoErrors("ids", "ids.0", "ids.1", "ids.2")
+		assert.List(t, input.Ints("ids"), []int{1, 2, 3})
+	}
+
+	input, res := testQueryString(o, "ids=1,x,0,1&tags=a,b")
+	assert.Validation(t, res).
+		Field("ids.1", InvalidIntType()).
+		Field("ids.2", InvalidIntMin(1)).
+		Field("ids.3", InvalidArrayUnique())
+	assert.List(t, input.Strings("tags"), []string{"a", "b"})
+
+	_, res = testQueryString(o, "")
+	assert.Validation(t, res).Field("ids", Required())
+
+	// present but empty
+	input, res = testQueryString(o, "ids=")
+	assert.Validation(t, res).FieldsHaveNoErrors("ids")
+	assert.Equal(t, len(input["ids"].([]any)), 0)
+}
+
+func Test_Args_Object(t *testing.T) {
+	o := Object().
+		Field("page", Int().Default(1)).
+		Field("filter", Object().
+			Field("name", String().Length(2, 10)).
+			Field("active", Bool()).
+			Field("range", Object().Field("min", Int().Required())))
+
+	input, res := testQueryString(o, "filter[name]=leto&filter[active]=true&filter[range][min]=3")
+	assert.Validation(t, res).FieldsHaveNoErrors("page", "filter.name", "filter.active", "filter.range.min")
+	assert.Equal(t, input.Int("page"), 1)
+	filter := input.Object("filter")
+	assert.Equal(t, filter.String("name"), "leto")
+	assert.True(t, filter.Bool("active"))
+	assert.Equal(t, filter.Object("range").Int("min"), 3)
+
+	_, res = testQueryString(o, "filter[name]=l&filter[active]=maybe&filter[range][max]=3")
+	assert.Validation(t, res).
+		Field("filter.name", InvalidStringLength(2, 10)).
+		Field("filter.active", InvalidBoolType()).
+		Field("filter.range.min", Required())
+
+	// unknown nested keys are ignored
+	input, _ = testQueryString(o, "filter[other]=1")
+	assert.Equal(t, len(input.Object("filter")), 0)
+}
+
+func Test_Args_ArrayOfObjects(t *testing.T) {
+	o := Object().Field("sort", Array().Max(2).Validator(Object().
+		Field("field", String().Required()).
+		Field("desc", Bool())))
+
+	input, res := testQueryString(o, "sort[1][field]=age&sort[0][field]=name&sort[0][desc]=true")
+	assert.Validation(t, res).FieldsHaveNoErrors("sort", "sort.0.field", "sort.1.field")
+	sort := input.Objects("sort")
+	assert.Equal(t, len(sort), 2)
+	assert.Equal(t, sort[0].String("field"), "name")
+	assert.True(t, sort[0].Bool("desc"))
+	assert.Equal(t, sort[1].String("field"), "age")
+
+	_, res = testQueryString(o, "sort[0][desc]=true&sort[5][field]=x&sort[9][field]=y")
+	assert.Validation(t, res).
+		Field("sort", InvalidArrayMaxLength(2)).
+		Field("sort.0.field", Required())
+
+	// a plain value is the wrong type, not missing
+	o = Object().Field("sort", Array().Required().Validator(Object().Field("field", String())))
+	_, res = testQueryString(o, "sort=name")
+	assert.Validation(t, res).Field("sort", InvalidArrayType())
+}
+
+func Test_Args_Map_PlainValue(t *testing.T) {
+	o := Object().Field("meta", Map().Required())
+	_, res := testQueryString(o, "meta=1")
+	assert.Validation(t, res).Field("meta", InvalidMapType())
+}
+
+func parseArgs(qs string) *fasthttp.Args {
+	args := new(fasthttp.Args)
+	args.Parse(qs)
+	return args
+}
+
+func testQueryString(o *ObjectValidator, qs string) (typed.Typed, *Result) {
+	args := parseArgs(qs)
+	res := NewResult(10)
+	input, _ := o.ValidateArgs(args, res)
+	return input, res
+}
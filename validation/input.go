@@ -40,14 +40,13 @@ rules and fields, but this should only be happening on startup.
 import (
 	"strings"
 
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils/typed"
 )
 
 type InputValidator interface {
 	addField(name string) InputValidator
 	validate(object typed.Typed, input typed.Typed, res *Result)
-	argsToTyped(args *fasthttp.Args, dest typed.Typed)
+	argsToTyped(args *queryArgs, dest typed.Typed)
 }
 
 /*
@@ -95,3 +94,19 @@ func (f Field) add(name string) Field {
 		Flat: flat,
 	}
 }
+
+// Rules are created with a default error (e.g. InvalidIntMin). This lets
+// the most recently added one be given a custom error instead.
+func setLastRuleError[T interface{ withError(Invalid) T }](rules []T, invalid Invalid) {
+	if len(rules) == 0 {
+		panic("validation: RuleError called without a rule")
+	}
+	last := len(rules) - 1
+	rules[last] = rules[last].withError(invalid)
+}
+
+// Func rules add their own errors, so a RuleError right after a Func
+// would otherwise be silently ignored.
+func panicFuncRuleError() {
+	panic("validation: RuleError can't be used with Func, which adds its own errors")
+}
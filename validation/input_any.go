@@ -1,13 +1,13 @@
 package validation
 
 import (
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils"
 	"src.sqlkite.com/utils/typed"
 )
 
 type AnyRule interface {
 	clone() AnyRule
+	withError(err Invalid) AnyRule
 	Validate(field Field, value any, object typed.Typed, input typed.Typed, res *Result) any
 }
 
@@ -25,7 +25,7 @@ type AnyValidator struct {
 	errReq   Invalid
 }
 
-func (v *AnyValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
+func (v *AnyValidator) argsToTyped(args *queryArgs, t typed.Typed) {
 	fieldName := v.field.Name
 	if value := args.Peek(fieldName); value != nil {
 		t[fieldName] = utils.B2S(value)
@@ -79,6 +79,12 @@ func (v *AnyValidator) Default(value any) *AnyValidator {
 	return v
 }
 
+// Replaces the error added when the field is missing
+func (v *AnyValidator) RequiredError(invalid Invalid) *AnyValidator {
+	v.errReq = invalid
+	return v
+}
+
 func (v *AnyValidator) Func(fn func(field Field, value any, object typed.Typed, input typed.Typed, res *Result) any) *AnyValidator {
 	v.rules = append(v.rules, AnyFunc{fn: fn})
 	return v
@@ -95,3 +101,9 @@ func (v AnyFunc) Validate(field Field, value any, object typed.Typed, input type
 func (r AnyFunc) clone() AnyRule {
 	return r
 }
+
+// Func rules add their own errors
+func (r AnyFunc) withError(err Invalid) AnyRule {
+	panicFuncRuleError()
+	return r
+}
@@ -1,12 +1,10 @@
 package validation
 
-import (
-	"github.com/valyala/fasthttp"
-	"src.sqlkite.com/utils/typed"
-)
+import "src.sqlkite.com/utils/typed"
 
 type ArrayRule interface {
 	clone() ArrayRule
+	withError(err Invalid) ArrayRule
 	Validate(field Field, value []typed.Typed, object typed.Typed, input typed.Typed, res *Result) []typed.Typed
 }
 
@@ -27,8 +25,22 @@ type ArrayValidator struct {
 	errType   Invalid
 }
 
-func (v *ArrayValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
-	panic("ArrayValidator.argstoType not supported")
+func (v *ArrayValidator) argsToTyped(args *queryArgs, t typed.Typed) {
+	fieldName := v.field.Name
+	if args.Object(fieldName) == nil {
+		if value := args.Peek(fieldName); value != nil {
+			// not an array, kept so that validate reports it
+			t[fieldName] = value
+		}
+		return
+	}
+
+	objects := args.Objects(fieldName)
+	values := make([]typed.Typed, len(objects))
+	for i, object := range objects {
+		values[i] = v.validator.argsToObject(object)
+	}
+	t[fieldName] = values
 }
 
 func (v *ArrayValidator) validate(object typed.Typed, input typed.Typed, res *Result) {
@@ -100,6 +112,25 @@ func (v *ArrayValidator) Default(value []typed.Typed) *ArrayValidator {
 	return v
 }
 
+// Replaces the error added when the field is missing
+func (v *ArrayValidator) RequiredError(invalid Invalid) *ArrayValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *ArrayValidator) TypeError(invalid Invalid) *ArrayValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Array().Min(1).RuleError(...)
+func (v *ArrayValidator) RuleError(invalid Invalid) *ArrayValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
 func (v *ArrayValidator) Validator(validator *ObjectValidator) *ArrayValidator {
 	v.validator = validator
 	return v
@@ -149,6 +180,11 @@ func (r ArrayMin) clone() ArrayRule {
 	}
 }
 
+func (r ArrayMin) withError(err Invalid) ArrayRule {
+	r.err = err
+	return r
+}
+
 type ArrayMax struct {
 	max int
 	err Invalid
@@ -168,6 +204,11 @@ func (r ArrayMax) clone() ArrayRule {
 	}
 }
 
+func (r ArrayMax) withError(err Invalid) ArrayRule {
+	r.err = err
+	return r
+}
+
 type ArrayRange struct {
 	min int
 	max int
@@ -188,3 +229,8 @@ func (r ArrayRange) clone() ArrayRule {
 		err: r.err,
 	}
 }
+
+func (r ArrayRange) withError(err Invalid) ArrayRule {
+	r.err = err
+	return r
+}
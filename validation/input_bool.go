@@ -1,12 +1,12 @@
 package validation
 
 import (
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils/typed"
 )
 
 type BoolRule interface {
 	clone() BoolRule
+	withError(err Invalid) BoolRule
 	Validate(field Field, value bool, object typed.Typed, input typed.Typed, res *Result) bool
 }
 
@@ -26,7 +26,7 @@ type BoolValidator struct {
 	errType  Invalid
 }
 
-func (v *BoolValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
+func (v *BoolValidator) argsToTyped(args *queryArgs, t typed.Typed) {
 	fieldName := v.field.Name
 	if value := args.Peek(fieldName); value != nil {
 		// switch string([]byte) is optimized by Go
@@ -93,6 +93,18 @@ func (v *BoolValidator) Default(value bool) *BoolValidator {
 	return v
 }
 
+// Replaces the error added when the field is missing
+func (v *BoolValidator) RequiredError(invalid Invalid) *BoolValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *BoolValidator) TypeError(invalid Invalid) *BoolValidator {
+	v.errType = invalid
+	return v
+}
+
 func (v *BoolValidator) Func(fn func(field Field, value bool, object typed.Typed, input typed.Typed, res *Result) bool) *BoolValidator {
 	v.rules = append(v.rules, BoolFunc{fn})
 	return v
@@ -109,3 +121,9 @@ func (v BoolFunc) Validate(field Field, value bool, object typed.Typed, input ty
 func (r BoolFunc) clone() BoolRule {
 	return r
 }
+
+// Func rules add their own errors
+func (r BoolFunc) withError(err Invalid) BoolRule {
+	panicFuncRuleError()
+	return r
+}
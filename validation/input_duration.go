@@ -0,0 +1,278 @@
+package validation
+
+/*
+Durations can be given as a Go duration string (e.g. "1h30m") or as a
+number of seconds. Either way, a time.Duration is stored in the input.
+*/
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/typed"
+)
+
+type DurationRule interface {
+	clone() DurationRule
+	withError(err Invalid) DurationRule
+	Validate(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration
+}
+
+func Duration() *DurationValidator {
+	return &DurationValidator{
+		errReq:  Required(),
+		errType: InvalidDurationType(),
+	}
+}
+
+type DurationValidator struct {
+	field    Field
+	dflt     time.Duration
+	required bool
+	rules    []DurationRule
+	errReq   Invalid
+	errType  Invalid
+}
+
+func (v *DurationValidator) argsToTyped(args *queryArgs, t typed.Typed) {
+	fieldName := v.field.Name
+	if value := args.Peek(fieldName); value != nil {
+		s := utils.B2S(value)
+		if n, err := strconv.ParseFloat(s, 64); err == nil {
+			if d, ok := secondsToDuration(n); ok {
+				t[fieldName] = d
+			} else {
+				t[fieldName] = value
+			}
+		} else if d, err := time.ParseDuration(s); err == nil {
+			t[fieldName] = d
+		} else {
+			t[fieldName] = value
+		}
+	}
+}
+
+func (v *DurationValidator) validate(object typed.Typed, input typed.Typed, res *Result) {
+	field := v.field
+	fieldName := field.Name
+
+	raw, exists := object[fieldName]
+	if !exists {
+		if v.required {
+			res.AddInvalidField(field, v.errReq)
+		} else if dflt := v.dflt; dflt != 0 {
+			object[fieldName] = dflt
+		}
+		return
+	}
+
+	value, ok := toDuration(raw)
+	if !ok {
+		res.AddInvalidField(field, v.errType)
+		return
+	}
+
+	for _, rule := range v.rules {
+		value = rule.Validate(field, value, object, input, res)
+	}
+	object[fieldName] = value
+}
+
+func (v *DurationValidator) addField(fieldName string) InputValidator {
+	field := v.field.add(fieldName)
+
+	rules := make([]DurationRule, len(v.rules))
+	for i, rule := range v.rules {
+		rules[i] = rule.clone()
+	}
+
+	return &DurationValidator{
+		field:    field,
+		dflt:     v.dflt,
+		required: v.required,
+		rules:    rules,
+		errReq:   v.errReq,
+		errType:  v.errType,
+	}
+}
+
+func (v *DurationValidator) Required() *DurationValidator {
+	v.required = true
+	return v
+}
+
+func (v *DurationValidator) Default(value time.Duration) *DurationValidator {
+	v.dflt = value
+	return v
+}
+
+// Replaces the error added when the field is missing
+func (v *DurationValidator) RequiredError(invalid Invalid) *DurationValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *DurationValidator) TypeError(invalid Invalid) *DurationValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Duration().Min(time.Second).RuleError(...)
+func (v *DurationValidator) RuleError(invalid Invalid) *DurationValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
+func (v *DurationValidator) Min(min time.Duration) *DurationValidator {
+	v.rules = append(v.rules, DurationMin{
+		min: min,
+		err: InvalidDurationMin(min),
+	})
+	return v
+}
+
+func (v *DurationValidator) Max(max time.Duration) *DurationValidator {
+	v.rules = append(v.rules, DurationMax{
+		max: max,
+		err: InvalidDurationMax(max),
+	})
+	return v
+}
+
+func (v *DurationValidator) Range(min time.Duration, max time.Duration) *DurationValidator {
+	v.rules = append(v.rules, DurationRange{
+		min: min,
+		max: max,
+		err: InvalidDurationRange(min, max),
+	})
+	return v
+}
+
+func (v *DurationValidator) Func(fn func(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration) *DurationValidator {
+	v.rules = append(v.rules, DurationFunc{fn: fn})
+	return v
+}
+
+type DurationMin struct {
+	min time.Duration
+	err Invalid
+}
+
+func (r DurationMin) Validate(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration {
+	if value < r.min {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r DurationMin) clone() DurationRule {
+	return DurationMin{
+		min: r.min,
+		err: r.err,
+	}
+}
+
+func (r DurationMin) withError(err Invalid) DurationRule {
+	r.err = err
+	return r
+}
+
+type DurationMax struct {
+	max time.Duration
+	err Invalid
+}
+
+func (r DurationMax) Validate(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration {
+	if value > r.max {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r DurationMax) clone() DurationRule {
+	return DurationMax{
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r DurationMax) withError(err Invalid) DurationRule {
+	r.err = err
+	return r
+}
+
+type DurationRange struct {
+	min time.Duration
+	max time.Duration
+	err Invalid
+}
+
+func (r DurationRange) Validate(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration {
+	if value < r.min || value > r.max {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r DurationRange) clone() DurationRule {
+	return DurationRange{
+		min: r.min,
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r DurationRange) withError(err Invalid) DurationRule {
+	r.err = err
+	return r
+}
+
+type DurationFunc struct {
+	fn func(Field, time.Duration, typed.Typed, typed.Typed, *Result) time.Duration
+}
+
+func (r DurationFunc) Validate(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration {
+	return r.fn(field, value, object, input, res)
+}
+
+func (r DurationFunc) clone() DurationRule {
+	return r
+}
+
+// Func rules add their own errors
+func (r DurationFunc) withError(err Invalid) DurationRule {
+	panicFuncRuleError()
+	return r
+}
+
+func toDuration(value any) (time.Duration, bool) {
+	switch v := value.(type) {
+	case time.Duration:
+		return v, true
+	case string:
+		d, err := time.ParseDuration(v)
+		return d, err == nil
+	case int:
+		return secondsToDuration(float64(v))
+	case int64:
+		return secondsToDuration(float64(v))
+	case float64:
+		return secondsToDuration(v)
+	}
+	return 0, false
+}
+
+// A time.Duration holds about 292 years. Anything larger (or NaN/Inf)
+// would overflow into a meaningless (possibly negative) duration.
+const maxDurationSeconds = float64(math.MaxInt64 / int64(time.Second))
+
+func secondsToDuration(seconds float64) (time.Duration, bool) {
+	if math.IsNaN(seconds) || seconds > maxDurationSeconds || seconds < -maxDurationSeconds {
+		return 0, false
+	}
+	return time.Duration(seconds * float64(time.Second)), true
+}
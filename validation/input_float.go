@@ -3,13 +3,13 @@ package validation
 import (
 	"strconv"
 
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils"
 	"src.sqlkite.com/utils/typed"
 )
 
 type FloatRule interface {
 	clone() FloatRule
+	withError(err Invalid) FloatRule
 	Validate(field Field, value float64, object typed.Typed, input typed.Typed, res *Result) float64
 }
 
@@ -29,7 +29,7 @@ type FloatValidator struct {
 	errType  Invalid
 }
 
-func (v *FloatValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
+func (v *FloatValidator) argsToTyped(args *queryArgs, t typed.Typed) {
 	fieldName := v.field.Name
 	if value := args.Peek(fieldName); value != nil {
 		if n, err := strconv.ParseFloat(utils.B2S(value), 64); err == nil {
@@ -92,6 +92,25 @@ func (v *FloatValidator) Default(value float64) *FloatValidator {
 	return v
 }
 
+// Replaces the error added when the field is missing
+func (v *FloatValidator) RequiredError(invalid Invalid) *FloatValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *FloatValidator) TypeError(invalid Invalid) *FloatValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Float().Min(1).RuleError(...)
+func (v *FloatValidator) RuleError(invalid Invalid) *FloatValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
 func (v *FloatValidator) Min(min float64) *FloatValidator {
 	v.rules = append(v.rules, FloatMin{
 		min: min,
@@ -141,6 +160,11 @@ func (r FloatMin) clone() FloatRule {
 	}
 }
 
+func (r FloatMin) withError(err Invalid) FloatRule {
+	r.err = err
+	return r
+}
+
 type FloatMax struct {
 	max float64
 	err Invalid
@@ -160,6 +184,11 @@ func (r FloatMax) clone() FloatRule {
 	}
 }
 
+func (r FloatMax) withError(err Invalid) FloatRule {
+	r.err = err
+	return r
+}
+
 type FloatRange struct {
 	min float64
 	max float64
@@ -181,6 +210,11 @@ func (r FloatRange) clone() FloatRule {
 	}
 }
 
+func (r FloatRange) withError(err Invalid) FloatRule {
+	r.err = err
+	return r
+}
+
 type FloatFunc struct {
 	fn func(Field, float64, typed.Typed, typed.Typed, *Result) float64
 }
@@ -192,3 +226,9 @@ func (v FloatFunc) Validate(field Field, value float64, object typed.Typed, inpu
 func (r FloatFunc) clone() FloatRule {
 	return r
 }
+
+// Func rules add their own errors
+func (r FloatFunc) withError(err Invalid) FloatRule {
+	panicFuncRuleError()
+	return r
+}
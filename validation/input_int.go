@@ -3,13 +3,13 @@ package validation
 import (
 	"strconv"
 
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils"
 	"src.sqlkite.com/utils/typed"
 )
 
 type IntRule interface {
 	clone() IntRule
+	withError(err Invalid) IntRule
 	Validate(field Field, value int, object typed.Typed, input typed.Typed, res *Result) int
 }
 
@@ -29,7 +29,7 @@ type IntValidator struct {
 	errType  Invalid
 }
 
-func (v *IntValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
+func (v *IntValidator) argsToTyped(args *queryArgs, t typed.Typed) {
 	fieldName := v.field.Name
 	if value := args.Peek(fieldName); value != nil {
 		if n, err := strconv.ParseInt(utils.B2S(value), 10, 0); err == nil {
@@ -92,6 +92,25 @@ func (v *IntValidator) Default(value int) *IntValidator {
 	return v
 }
 
+// Replaces the error added when the field is missing
+func (v *IntValidator) RequiredError(invalid Invalid) *IntValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *IntValidator) TypeError(invalid Invalid) *IntValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Int().Min(1).RuleError(...)
+func (v *IntValidator) RuleError(invalid Invalid) *IntValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
 func (v *IntValidator) Min(min int) *IntValidator {
 	v.rules = append(v.rules, IntMin{
 		min: min,
@@ -141,6 +160,11 @@ func (r IntMin) clone() IntRule {
 	}
 }
 
+func (r IntMin) withError(err Invalid) IntRule {
+	r.err = err
+	return r
+}
+
 type IntMax struct {
 	max int
 	err Invalid
@@ -160,6 +184,11 @@ func (r IntMax) clone() IntRule {
 	}
 }
 
+func (r IntMax) withError(err Invalid) IntRule {
+	r.err = err
+	return r
+}
+
 type IntRange struct {
 	min int
 	max int
@@ -181,6 +210,11 @@ func (r IntRange) clone() IntRule {
 	}
 }
 
+func (r IntRange) withError(err Invalid) IntRule {
+	r.err = err
+	return r
+}
+
 type IntFunc struct {
 	fn func(Field, int, typed.Typed, typed.Typed, *Result) int
 }
@@ -192,3 +226,9 @@ func (v IntFunc) Validate(field Field, value int, object typed.Typed, input type
 func (r IntFunc) clone() IntRule {
 	return r
 }
+
+// Func rules add their own errors
+func (r IntFunc) withError(err Invalid) IntRule {
+	panicFuncRuleError()
+	return r
+}
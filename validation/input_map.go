@@ -0,0 +1,362 @@
+package validation
+
+/*
+Objects with arbitrary keys, e.g. `"labels": {"env": "prod"}`:
+
+	Object().Field("labels", Map().
+		Max(20).
+		Key(String().Length(1, 50).Pattern("^[a-z_]+$")).
+		Value(String().Length(0, 200)))
+
+Keys are validated by a StringValidator and values by any validator.
+Like ScalarArray, both are bound with a Name of "" and given each key
+and value wrapped in a typed.Typed{"": x}. Errors for a key, or its
+value, are reported against the key, e.g. "labels.env".
+
+If the key validator changes the key (e.g. String().Lower()), the value
+is moved to the new key. Two keys that end up the same (e.g. "ENV" and
+"env") are reported as duplicates; keys are processed in sorted order,
+and the first one wins.
+*/
+
+import (
+	"sort"
+
+	"src.sqlkite.com/utils/typed"
+)
+
+type MapRule interface {
+	clone() MapRule
+	withError(err Invalid) MapRule
+	Validate(field Field, value typed.Typed, object typed.Typed, input typed.Typed, res *Result) typed.Typed
+}
+
+func Map() *MapValidator {
+	return &MapValidator{
+		errReq:       Required(),
+		errType:      InvalidMapType(),
+		errDuplicate: InvalidMapDuplicateKey(),
+	}
+}
+
+type MapValidator struct {
+	field    Field
+	item     Field
+	required bool
+	dflt     map[string]any
+	rules    []MapRule
+
+	// the validators as given to Key and Value, which we re-bind to our
+	// field every time addField is called
+	keys   *StringValidator
+	values InputValidator
+
+	// keys and values bound to our field
+	keyValidator   InputValidator
+	valueValidator InputValidator
+
+	errReq       Invalid
+	errType      Invalid
+	errDuplicate Invalid
+}
+
+func (v *MapValidator) argsToTyped(args *queryArgs, t typed.Typed) {
+	fieldName := v.field.Name
+	object := args.Object(fieldName)
+	if object == nil {
+		if value := args.Peek(fieldName); value != nil {
+			// not a map, kept so that validate reports it
+			t[fieldName] = value
+		}
+		return
+	}
+
+	m := make(typed.Typed, len(object.values)+len(object.objects))
+	validator := v.valueValidator
+	if validator == nil {
+		for key := range object.values {
+			m[key] = string(object.Peek(key))
+		}
+		t[fieldName] = m
+		return
+	}
+
+	// let the value validator convert each value as it would a field
+	// (e.g. "1" => 1 for Int, or labels[env][name]=x for an Object)
+	holder := make(typed.Typed, 1)
+	convert := func(key string) {
+		item := &queryArgs{}
+		if values, ok := object.values[key]; ok {
+			item.values = map[string][][]byte{"": values}
+		}
+		if o, ok := object.objects[key]; ok {
+			item.objects = map[string]*queryArgs{"": o}
+		}
+		delete(holder, "")
+		validator.argsToTyped(item, holder)
+		if value, ok := holder[""]; ok {
+			m[key] = value
+		}
+	}
+	for key := range object.values {
+		convert(key)
+	}
+	for key := range object.objects {
+		if _, done := object.values[key]; !done {
+			convert(key)
+		}
+	}
+	t[fieldName] = m
+}
+
+func (v *MapValidator) validate(object typed.Typed, input typed.Typed, res *Result) {
+	field := v.field
+	fieldName := field.Name
+
+	value, exists := object.ObjectIf(fieldName)
+	if !exists {
+		if _, exists := object[fieldName]; !exists {
+			if v.required {
+				res.AddInvalidField(field, v.errReq)
+			} else if dflt := v.dflt; dflt != nil {
+				object[fieldName] = dflt
+			}
+			return
+		}
+		res.AddInvalidField(field, v.errType)
+		return
+	}
+
+	for _, rule := range v.rules {
+		value = rule.Validate(field, value, object, input, res)
+	}
+
+	keyValidator := v.keyValidator
+	valueValidator := v.valueValidator
+	if keyValidator == nil && valueValidator == nil {
+		return
+	}
+
+	// map iteration is random, keep the errors in a stable order
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// built separately, so that renamed keys don't collide with (or get
+	// processed again as) keys we haven't seen yet
+	out := make(typed.Typed, len(value))
+
+	res.beginArray()
+	holder := make(typed.Typed, 1)
+	for _, key := range keys {
+		res.mapKey(key)
+
+		newKey := key
+		if keyValidator != nil {
+			holder[""] = key
+			keyValidator.validate(holder, input, res)
+			if s, ok := holder[""].(string); ok {
+				newKey = s
+			}
+		}
+
+		if _, exists := out[newKey]; exists {
+			res.AddInvalidField(v.item, v.errDuplicate)
+			continue
+		}
+
+		item := value[key]
+		if valueValidator != nil {
+			holder[""] = item
+			valueValidator.validate(holder, input, res)
+			item = holder[""]
+		}
+		out[newKey] = item
+	}
+	res.endArray()
+
+	object[fieldName] = out
+}
+
+func (v *MapValidator) addField(fieldName string) InputValidator {
+	field := v.field.add(fieldName)
+
+	rules := make([]MapRule, len(v.rules))
+	for i, rule := range v.rules {
+		rules[i] = rule.clone()
+	}
+
+	var keyValidator InputValidator
+	if keys := v.keys; keys != nil {
+		keyValidator = bindItems(keys, field)
+	}
+
+	var valueValidator InputValidator
+	if values := v.values; values != nil {
+		valueValidator = bindItems(values, field)
+	}
+
+	return &MapValidator{
+		field:          field,
+		item:           bindField(Field{}.add(""), field),
+		required:       v.required,
+		dflt:           v.dflt,
+		rules:          rules,
+		keys:           v.keys,
+		values:         v.values,
+		keyValidator:   keyValidator,
+		valueValidator: valueValidator,
+		errReq:         v.errReq,
+		errType:        v.errType,
+		errDuplicate:   v.errDuplicate,
+	}
+}
+
+func (v *MapValidator) Required() *MapValidator {
+	v.required = true
+	return v
+}
+
+func (v *MapValidator) Default(value map[string]any) *MapValidator {
+	v.dflt = value
+	return v
+}
+
+// Replaces the error added when the field is missing
+func (v *MapValidator) RequiredError(invalid Invalid) *MapValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *MapValidator) TypeError(invalid Invalid) *MapValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Map().Max(10).RuleError(...)
+func (v *MapValidator) RuleError(invalid Invalid) *MapValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
+// Replaces the error added when two keys are the same once changed by
+// the key validator
+func (v *MapValidator) DuplicateKeyError(invalid Invalid) *MapValidator {
+	v.errDuplicate = invalid
+	return v
+}
+
+// The validator applied to each key, e.g. String().Pattern("^[a-z]+$")
+func (v *MapValidator) Key(validator *StringValidator) *MapValidator {
+	v.keys = validator
+	return v
+}
+
+// The validator applied to each value
+func (v *MapValidator) Value(validator InputValidator) *MapValidator {
+	v.values = validator
+	return v
+}
+
+func (v *MapValidator) Min(min int) *MapValidator {
+	v.rules = append(v.rules, MapMin{
+		min: min,
+		err: InvalidMapMinKeys(min),
+	})
+	return v
+}
+
+func (v *MapValidator) Max(max int) *MapValidator {
+	v.rules = append(v.rules, MapMax{
+		max: max,
+		err: InvalidMapMaxKeys(max),
+	})
+	return v
+}
+
+func (v *MapValidator) Range(min int, max int) *MapValidator {
+	v.rules = append(v.rules, MapRange{
+		min: min,
+		max: max,
+		err: InvalidMapRangeKeys(min, max),
+	})
+	return v
+}
+
+type MapMin struct {
+	min int
+	err Invalid
+}
+
+func (r MapMin) Validate(field Field, value typed.Typed, object typed.Typed, input typed.Typed, res *Result) typed.Typed {
+	if len(value) < r.min {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r MapMin) clone() MapRule {
+	return MapMin{
+		min: r.min,
+		err: r.err,
+	}
+}
+
+func (r MapMin) withError(err Invalid) MapRule {
+	r.err = err
+	return r
+}
+
+type MapMax struct {
+	max int
+	err Invalid
+}
+
+func (r MapMax) Validate(field Field, value typed.Typed, object typed.Typed, input typed.Typed, res *Result) typed.Typed {
+	if len(value) > r.max {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r MapMax) clone() MapRule {
+	return MapMax{
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r MapMax) withError(err Invalid) MapRule {
+	r.err = err
+	return r
+}
+
+type MapRange struct {
+	min int
+	max int
+	err Invalid
+}
+
+func (r MapRange) Validate(field Field, value typed.Typed, object typed.Typed, input typed.Typed, res *Result) typed.Typed {
+	if l := len(value); l < r.min || l > r.max {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r MapRange) clone() MapRule {
+	return MapRange{
+		min: r.min,
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r MapRange) withError(err Invalid) MapRule {
+	r.err = err
+	return r
+}
@@ -1,53 +1,142 @@
 package validation
 
 import (
+	"sort"
+	"strings"
+	"time"
+
 	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils/typed"
 )
 
+/*
+By default, keys that have no validator are ignored (and left in the
+input). Strict reports them as invalid, Strip deletes them. The mode
+only applies to the object it's set on, so a nested object (or the
+object given to an Array) needs its own Strict or Strip.
+
+Fields referenced by an object rule (e.g. RequiredWith) are known
+fields, even without a validator.
+
+ValidateArgs only copies the query string's known keys into the input,
+so unknown keys are always dropped there. Strict still reports them
+(including those of nested objects, e.g. ?filter[x]=1).
+*/
+type unknownMode uint8
+
+const (
+	unknownIgnore unknownMode = iota
+	unknownReject
+	unknownStrip
+)
+
 func Object() *ObjectValidator {
-	return &ObjectValidator{}
+	return &ObjectValidator{
+		errUnknown: InvalidUnknownField(),
+	}
 }
 
 type ObjectValidator struct {
 	field      Field
 	validators []InputValidator
+	rules      []ObjectRule
+	known      map[string]struct{}
+	unknown    unknownMode
+	errUnknown Invalid
 }
 
 func (o *ObjectValidator) Field(fieldName string, validator InputValidator) *ObjectValidator {
 	o.validators = append(o.validators, validator.addField(fieldName))
+	o.know(fieldName)
+	return o
+}
+
+// Unknown fields are invalid
+func (o *ObjectValidator) Strict() *ObjectValidator {
+	o.unknown = unknownReject
+	return o
+}
+
+// Unknown fields are removed from the input
+func (o *ObjectValidator) Strip() *ObjectValidator {
+	o.unknown = unknownStrip
+	return o
+}
+
+// Replaces the error added for unknown fields in Strict mode
+func (o *ObjectValidator) UnknownError(invalid Invalid) *ObjectValidator {
+	o.errUnknown = invalid
 	return o
 }
 
 // object validation called on the root
 func (o *ObjectValidator) Validate(input typed.Typed, res *Result) bool {
 	len := res.Len()
+	o.unknownFields(input, res)
 	for _, validator := range o.validators {
 		validator.validate(input, input, res)
 	}
+	for _, rule := range o.rules {
+		rule.Validate(input, input, res)
+	}
 	return res.Len() == len
 }
 
+// Validates a query string. See args.go for how repeated keys, lists
+// and nested objects are decoded.
 func (o *ObjectValidator) ValidateArgs(args *fasthttp.Args, res *Result) (typed.Typed, bool) {
-	validators := o.validators
-	input := make(typed.Typed, len(validators))
-	for _, validator := range validators {
-		validator.argsToTyped(args, input)
-	}
+	input := o.argsToObject(newQueryArgs(args))
 	return input, o.Validate(input, res)
 }
 
 // called when the object is nested, unlike the public Validate which is
 // the main entry point into validation.
 func (v *ObjectValidator) validate(object typed.Typed, input typed.Typed, res *Result) {
-	object = object.Object(v.field.Name)
+	object, exists := object.ObjectIf(v.field.Name)
+	if exists {
+		v.unknownFields(object, res)
+	}
 	for _, validator := range v.validators {
 		validator.validate(object, input, res)
 	}
+	if !exists {
+		// the object's own validators take care of required fields, but
+		// rules like OneOf shouldn't apply to a missing optional object
+		return
+	}
+	for _, rule := range v.rules {
+		rule.Validate(object, input, res)
+	}
 }
 
-func (v *ObjectValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
-	panic("ObjectValidator.argstoType not supported")
+func (v *ObjectValidator) argsToTyped(args *queryArgs, t typed.Typed) {
+	if object := args.Object(v.field.Name); object != nil {
+		t[v.field.Name] = v.argsToObject(object)
+	}
+}
+
+func (v *ObjectValidator) argsToObject(args *queryArgs) typed.Typed {
+	validators := v.validators
+	object := make(typed.Typed, len(validators))
+	for _, validator := range validators {
+		validator.argsToTyped(args, object)
+	}
+
+	if v.unknown == unknownReject {
+		// copied so that unknownFields reports them
+		known := v.known
+		for key := range args.values {
+			if _, ok := known[key]; !ok {
+				object[key] = string(args.Peek(key))
+			}
+		}
+		for key, nested := range args.objects {
+			if _, ok := known[key]; !ok {
+				object[key] = nested.raw()
+			}
+		}
+	}
+	return object
 }
 
 func (v *ObjectValidator) addField(fieldName string) InputValidator {
@@ -55,9 +144,413 @@ func (v *ObjectValidator) addField(fieldName string) InputValidator {
 	for i, validator := range v.validators {
 		validators[i] = validator.addField(fieldName)
 	}
+	rules := make([]ObjectRule, len(v.rules))
+	for i, rule := range v.rules {
+		rules[i] = rule.addField(fieldName)
+	}
+	known := make(map[string]struct{}, len(v.known))
+	for name := range v.known {
+		known[name] = struct{}{}
+	}
+
 	field := v.field.add(fieldName)
 	return &ObjectValidator{
 		field:      field,
 		validators: validators,
+		rules:      rules,
+		known:      known,
+		unknown:    v.unknown,
+		errUnknown: v.errUnknown,
+	}
+}
+
+func (v *ObjectValidator) know(fieldNames ...string) {
+	if v.known == nil {
+		v.known = make(map[string]struct{}, len(fieldNames))
+	}
+	for _, fieldName := range fieldNames {
+		v.known[fieldName] = struct{}{}
+	}
+}
+
+func (v *ObjectValidator) unknownFields(object typed.Typed, res *Result) {
+	if v.unknown == unknownIgnore {
+		return
+	}
+
+	var unknown []string
+	known := v.known
+	for key := range object {
+		if _, ok := known[key]; !ok {
+			unknown = append(unknown, key)
+		}
+	}
+
+	if v.unknown == unknownStrip {
+		for _, key := range unknown {
+			delete(object, key)
+		}
+		return
+	}
+
+	// map iteration is random, keep the errors in a stable order
+	sort.Strings(unknown)
+	for _, key := range unknown {
+		res.AddInvalidField(bindField(Field{}.add(key), v.field), v.errUnknown)
+	}
+}
+
+// fieldName is required when other is equal to value
+func (v *ObjectValidator) RequiredIf(fieldName string, other string, value any) *ObjectValidator {
+	v.know(fieldName, other)
+	v.rules = append(v.rules, ObjectRequiredIf{
+		field: Field{}.add(fieldName),
+		other: other,
+		value: value,
+		err:   Required(),
+	})
+	return v
+}
+
+// fieldName is required when any of others is present
+func (v *ObjectValidator) RequiredWith(fieldName string, others ...string) *ObjectValidator {
+	v.know(fieldName)
+	v.know(others...)
+	v.rules = append(v.rules, ObjectRequiredWith{
+		field:  Field{}.add(fieldName),
+		others: others,
+		err:    Required(),
+	})
+	return v
+}
+
+// When present, fieldName must be equal to other (e.g. a password
+// confirmation)
+func (v *ObjectValidator) EqualField(fieldName string, other string) *ObjectValidator {
+	v.know(fieldName, other)
+	v.rules = append(v.rules, ObjectEqualField{
+		field: Field{}.add(fieldName),
+		other: other,
+		err:   InvalidFieldEqual(other),
+	})
+	return v
+}
+
+// When both are present, fieldName must be greater than other. Works
+// on numbers, strings (so ISO 8601 dates compare correctly), times and
+// durations.
+func (v *ObjectValidator) GreaterThanField(fieldName string, other string) *ObjectValidator {
+	v.know(fieldName, other)
+	v.rules = append(v.rules, ObjectGreaterThanField{
+		field: Field{}.add(fieldName),
+		other: other,
+		err:   InvalidFieldGreater(other),
+	})
+	return v
+}
+
+// At most one of the fields can be present. Every present field after
+// the first is invalid.
+func (v *ObjectValidator) MutuallyExclusive(fieldNames ...string) *ObjectValidator {
+	v.know(fieldNames...)
+	v.rules = append(v.rules, ObjectMutuallyExclusive{
+		fields: objectRuleFields(fieldNames),
+		err:    InvalidFieldsExclusive(fieldNames),
+	})
+	return v
+}
+
+// Exactly one of the fields must be present. If none are, every field
+// is invalid, if more than one is, every present field after the first
+// is.
+func (v *ObjectValidator) OneOf(fieldNames ...string) *ObjectValidator {
+	v.know(fieldNames...)
+	v.rules = append(v.rules, ObjectOneOf{
+		fields: objectRuleFields(fieldNames),
+		err:    InvalidFieldsOneOf(fieldNames),
+	})
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Object().EqualField("confirm", "password").RuleError(...)
+func (v *ObjectValidator) RuleError(invalid Invalid) *ObjectValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
+/*
+Rules that involve more than one field of an object. They run after all
+of the object's field validators, so they see converted values (e.g. an
+Int field's string query arg will already be an int).
+
+Unlike other rules, these aren't given the field to report errors
+against, since they know about multiple fields. Instead, they hold
+their own Fields, which get bound to the object's path (via addField)
+the same way the object's validators are.
+
+A field is "present" when it exists and isn't null.
+*/
+type ObjectRule interface {
+	addField(fieldName string) ObjectRule
+	withError(err Invalid) ObjectRule
+	Validate(object typed.Typed, input typed.Typed, res *Result)
+}
+
+type ObjectRequiredIf struct {
+	field Field
+	other string
+	value any
+	err   Invalid
+}
+
+func (r ObjectRequiredIf) Validate(object typed.Typed, input typed.Typed, res *Result) {
+	if !present(object, r.field.Name) && equalValues(object[r.other], r.value) {
+		res.AddInvalidField(r.field, r.err)
+	}
+}
+
+func (r ObjectRequiredIf) addField(fieldName string) ObjectRule {
+	r.field = r.field.add(fieldName)
+	return r
+}
+
+func (r ObjectRequiredIf) withError(err Invalid) ObjectRule {
+	r.err = err
+	return r
+}
+
+type ObjectRequiredWith struct {
+	field  Field
+	others []string
+	err    Invalid
+}
+
+func (r ObjectRequiredWith) Validate(object typed.Typed, input typed.Typed, res *Result) {
+	if present(object, r.field.Name) {
+		return
+	}
+	for _, other := range r.others {
+		if present(object, other) {
+			res.AddInvalidField(r.field, r.err)
+			return
+		}
+	}
+}
+
+func (r ObjectRequiredWith) addField(fieldName string) ObjectRule {
+	r.field = r.field.add(fieldName)
+	return r
+}
+
+func (r ObjectRequiredWith) withError(err Invalid) ObjectRule {
+	r.err = err
+	return r
+}
+
+type ObjectEqualField struct {
+	field Field
+	other string
+	err   Invalid
+}
+
+func (r ObjectEqualField) Validate(object typed.Typed, input typed.Typed, res *Result) {
+	fieldName := r.field.Name
+	if present(object, fieldName) && !equalValues(object[fieldName], object[r.other]) {
+		res.AddInvalidField(r.field, r.err)
+	}
+}
+
+func (r ObjectEqualField) addField(fieldName string) ObjectRule {
+	r.field = r.field.add(fieldName)
+	return r
+}
+
+func (r ObjectEqualField) withError(err Invalid) ObjectRule {
+	r.err = err
+	return r
+}
+
+type ObjectGreaterThanField struct {
+	field Field
+	other string
+	err   Invalid
+}
+
+func (r ObjectGreaterThanField) Validate(object typed.Typed, input typed.Typed, res *Result) {
+	// missing or mismatched types are left to the fields' own validators
+	if c, ok := compareValues(object[r.field.Name], object[r.other]); ok && c <= 0 {
+		res.AddInvalidField(r.field, r.err)
+	}
+}
+
+func (r ObjectGreaterThanField) addField(fieldName string) ObjectRule {
+	r.field = r.field.add(fieldName)
+	return r
+}
+
+func (r ObjectGreaterThanField) withError(err Invalid) ObjectRule {
+	r.err = err
+	return r
+}
+
+type ObjectMutuallyExclusive struct {
+	fields []Field
+	err    Invalid
+}
+
+func (r ObjectMutuallyExclusive) Validate(object typed.Typed, input typed.Typed, res *Result) {
+	found := false
+	for _, field := range r.fields {
+		if present(object, field.Name) {
+			if found {
+				res.AddInvalidField(field, r.err)
+			}
+			found = true
+		}
+	}
+}
+
+func (r ObjectMutuallyExclusive) addField(fieldName string) ObjectRule {
+	r.fields = addObjectRuleFields(r.fields, fieldName)
+	return r
+}
+
+func (r ObjectMutuallyExclusive) withError(err Invalid) ObjectRule {
+	r.err = err
+	return r
+}
+
+type ObjectOneOf struct {
+	fields []Field
+	err    Invalid
+}
+
+func (r ObjectOneOf) Validate(object typed.Typed, input typed.Typed, res *Result) {
+	found := false
+	for _, field := range r.fields {
+		if present(object, field.Name) {
+			if found {
+				res.AddInvalidField(field, r.err)
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		for _, field := range r.fields {
+			res.AddInvalidField(field, r.err)
+		}
+	}
+}
+
+func (r ObjectOneOf) addField(fieldName string) ObjectRule {
+	r.fields = addObjectRuleFields(r.fields, fieldName)
+	return r
+}
+
+func (r ObjectOneOf) withError(err Invalid) ObjectRule {
+	r.err = err
+	return r
+}
+
+func objectRuleFields(fieldNames []string) []Field {
+	fields := make([]Field, len(fieldNames))
+	for i, fieldName := range fieldNames {
+		fields[i] = Field{}.add(fieldName)
+	}
+	return fields
+}
+
+func addObjectRuleFields(fields []Field, fieldName string) []Field {
+	added := make([]Field, len(fields))
+	for i, field := range fields {
+		added[i] = field.add(fieldName)
+	}
+	return added
+}
+
+func present(object typed.Typed, fieldName string) bool {
+	value, exists := object[fieldName]
+	return exists && value != nil
+}
+
+// 1 and 1.0 are equal (see uniqueKey). Non-scalars never are.
+func equalValues(a any, b any) bool {
+	switch ta := a.(type) {
+	case time.Time:
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	case time.Duration:
+		db, ok := b.(time.Duration)
+		return ok && ta == db
+	}
+
+	ka, ok := uniqueKey(a)
+	if !ok {
+		return false
+	}
+	kb, ok := uniqueKey(b)
+	return ok && ka == kb
+}
+
+// Compares two numbers, strings, times or durations. Returns false if
+// the values can't be compared.
+func compareValues(a any, b any) (int, bool) {
+	switch va := a.(type) {
+	case string:
+		if vb, ok := b.(string); ok {
+			return strings.Compare(va, vb), true
+		}
+		return 0, false
+	case time.Time:
+		if vb, ok := b.(time.Time); ok {
+			switch {
+			case va.Before(vb):
+				return -1, true
+			case va.After(vb):
+				return 1, true
+			}
+			return 0, true
+		}
+		return 0, false
+	case time.Duration:
+		if vb, ok := b.(time.Duration); ok {
+			return compareOrdered(va, vb), true
+		}
+		return 0, false
+	}
+
+	fa, ok := toFloat(a)
+	if !ok {
+		return 0, false
+	}
+	fb, ok := toFloat(b)
+	if !ok {
+		return 0, false
+	}
+
+	return compareOrdered(fa, fb), true
+}
+
+func compareOrdered[T float64 | time.Duration](a T, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+func toFloat(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
 	}
+	return 0, false
 }
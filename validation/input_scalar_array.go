@@ -0,0 +1,378 @@
+package validation
+
+/*
+Arrays of scalars (strings, ints, floats, bools, uuids), e.g.
+`"tags": ["a", "b"]`, where each item is validated by a scalar
+validator:
+
+	Object().Field("tags", ScalarArray().Max(10).Unique().Validator(String().Length(1, 20)))
+
+Scalar validators validate object[field.Name]. The item validator gets
+a Name of "" (the same placeholder used for array indexes), so we
+validate each item by wrapping it in a typed.Typed{"": item}. Errors
+are reported against the item's index, e.g. "tags.3".
+*/
+
+import "src.sqlkite.com/utils/typed"
+
+type ScalarArrayRule interface {
+	clone() ScalarArrayRule
+	withError(err Invalid) ScalarArrayRule
+	Validate(field Field, value []any, object typed.Typed, input typed.Typed, res *Result) []any
+}
+
+func ScalarArray() *ScalarArrayValidator {
+	return &ScalarArrayValidator{
+		errReq:    Required(),
+		errType:   InvalidArrayType(),
+		errUnique: InvalidArrayUnique(),
+	}
+}
+
+type ScalarArrayValidator struct {
+	field    Field
+	item     Field
+	required bool
+	unique   bool
+	dflt     []any
+	rules    []ScalarArrayRule
+
+	// the validator as given to Validator, which we re-bind to our field
+	// every time addField is called
+	items InputValidator
+
+	// items bound to our field
+	validator InputValidator
+
+	errReq    Invalid
+	errType   Invalid
+	errUnique Invalid
+}
+
+func (v *ScalarArrayValidator) argsToTyped(args *queryArgs, t typed.Typed) {
+	fieldName := v.field.Name
+	list, exists := args.PeekList(fieldName)
+	if !exists {
+		return
+	}
+
+	values := make([]any, len(list))
+	validator := v.validator
+	if validator == nil {
+		for i, value := range list {
+			values[i] = string(value)
+		}
+	} else {
+		// let the item validator convert each value as it would a
+		// scalar field (e.g. "1" => 1 for Int)
+		item := &queryArgs{values: make(map[string][][]byte, 1)}
+		holder := make(typed.Typed, 1)
+		for i, value := range list {
+			item.values[""] = [][]byte{value}
+			validator.argsToTyped(item, holder)
+			values[i] = holder[""]
+		}
+	}
+	t[fieldName] = values
+}
+
+func (v *ScalarArrayValidator) validate(object typed.Typed, input typed.Typed, res *Result) {
+	field := v.field
+	fieldName := field.Name
+
+	raw, exists := object[fieldName]
+	if !exists {
+		if v.required {
+			res.AddInvalidField(field, v.errReq)
+		} else if dflt := v.dflt; dflt != nil {
+			object[fieldName] = dflt
+		}
+		return
+	}
+
+	values, ok := toAnySlice(raw)
+	if !ok {
+		res.AddInvalidField(field, v.errType)
+		return
+	}
+
+	for _, rule := range v.rules {
+		values = rule.Validate(field, values, object, input, res)
+	}
+
+	var seen map[any]struct{}
+	if v.unique {
+		seen = make(map[any]struct{}, len(values))
+	}
+
+	res.beginArray()
+	validator := v.validator
+	holder := make(typed.Typed, 1)
+	for i, value := range values {
+		res.arrayIndex(i)
+		if validator != nil {
+			holder[""] = value
+			l := res.Len()
+			validator.validate(holder, input, res)
+			value = holder[""]
+			values[i] = value
+			if res.Len() != l {
+				// don't also report invalid values as duplicates
+				continue
+			}
+		}
+
+		if seen != nil {
+			if key, ok := uniqueKey(value); ok {
+				if _, exists := seen[key]; exists {
+					res.AddInvalidField(v.item, v.errUnique)
+				} else {
+					seen[key] = struct{}{}
+				}
+			}
+		}
+	}
+	res.endArray()
+
+	object[fieldName] = values
+}
+
+func (v *ScalarArrayValidator) addField(fieldName string) InputValidator {
+	field := v.field.add(fieldName)
+
+	rules := make([]ScalarArrayRule, len(v.rules))
+	for i, rule := range v.rules {
+		rules[i] = rule.clone()
+	}
+
+	var validator InputValidator
+	if items := v.items; items != nil {
+		validator = bindItems(items, field)
+	}
+
+	return &ScalarArrayValidator{
+		field:     field,
+		item:      bindField(Field{}.add(""), field),
+		required:  v.required,
+		unique:    v.unique,
+		dflt:      v.dflt,
+		rules:     rules,
+		items:     v.items,
+		validator: validator,
+		errReq:    v.errReq,
+		errType:   v.errType,
+		errUnique: v.errUnique,
+	}
+}
+
+func (v *ScalarArrayValidator) Required() *ScalarArrayValidator {
+	v.required = true
+	return v
+}
+
+func (v *ScalarArrayValidator) Default(value []any) *ScalarArrayValidator {
+	v.dflt = value
+	return v
+}
+
+// Replaces the error added when the field is missing
+func (v *ScalarArrayValidator) RequiredError(invalid Invalid) *ScalarArrayValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *ScalarArrayValidator) TypeError(invalid Invalid) *ScalarArrayValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error added for duplicate items
+func (v *ScalarArrayValidator) UniqueError(invalid Invalid) *ScalarArrayValidator {
+	v.errUnique = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// ScalarArray().Min(1).RuleError(...)
+func (v *ScalarArrayValidator) RuleError(invalid Invalid) *ScalarArrayValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
+// The validator applied to each item, e.g. String().Length(1, 20)
+func (v *ScalarArrayValidator) Validator(validator InputValidator) *ScalarArrayValidator {
+	v.items = validator
+	return v
+}
+
+// Items must be unique. Duplicates (every occurrence after the first)
+// are reported against their index.
+func (v *ScalarArrayValidator) Unique() *ScalarArrayValidator {
+	v.unique = true
+	return v
+}
+
+func (v *ScalarArrayValidator) Min(min int) *ScalarArrayValidator {
+	v.rules = append(v.rules, ScalarArrayMin{
+		min: min,
+		err: InvalidArrayMinLength(min),
+	})
+	return v
+}
+
+func (v *ScalarArrayValidator) Max(max int) *ScalarArrayValidator {
+	v.rules = append(v.rules, ScalarArrayMax{
+		max: max,
+		err: InvalidArrayMaxLength(max),
+	})
+	return v
+}
+
+func (v *ScalarArrayValidator) Range(min int, max int) *ScalarArrayValidator {
+	v.rules = append(v.rules, ScalarArrayRange{
+		min: min,
+		max: max,
+		err: InvalidArrayRangeLength(min, max),
+	})
+	return v
+}
+
+// Binds the item validator to the array's field. The item gets a name
+// of "" and a path of the array's path followed by "" (which
+// Result.AddInvalidField replaces with the index).
+func bindItems(items InputValidator, field Field) InputValidator {
+	validator := items.addField("")
+	path := field.Path
+	for i := len(path) - 1; i >= 0; i-- {
+		validator = validator.addField(path[i])
+	}
+	return validator
+}
+
+// The field of an item, with a placeholder for its index
+func bindField(item Field, field Field) Field {
+	path := field.Path
+	for i := len(path) - 1; i >= 0; i-- {
+		item = item.add(path[i])
+	}
+	return item
+}
+
+// JSON gives us []any, but values set in code (or by defaults) can be
+// typed slices.
+func toAnySlice(value any) ([]any, bool) {
+	switch v := value.(type) {
+	case []any:
+		return v, true
+	case []string:
+		return copyToAny(v), true
+	case []int:
+		return copyToAny(v), true
+	case []int64:
+		return copyToAny(v), true
+	case []float64:
+		return copyToAny(v), true
+	case []bool:
+		return copyToAny(v), true
+	}
+	return nil, false
+}
+
+func copyToAny[T any](values []T) []any {
+	out := make([]any, len(values))
+	for i, value := range values {
+		out[i] = value
+	}
+	return out
+}
+
+// Numbers can be either ints or float64s (depending on whether the
+// item was converted by an Int validator), 1 and 1.0 should be equal.
+// Non-scalars (which we'd only see without an item validator) can't be
+// compared.
+func uniqueKey(value any) (any, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case float64, string, bool, nil:
+		return v, true
+	}
+	return nil, false
+}
+
+type ScalarArrayMin struct {
+	min int
+	err Invalid
+}
+
+func (r ScalarArrayMin) Validate(field Field, values []any, object typed.Typed, input typed.Typed, res *Result) []any {
+	if len(values) < r.min {
+		res.AddInvalidField(field, r.err)
+	}
+	return values
+}
+
+func (r ScalarArrayMin) clone() ScalarArrayRule {
+	return ScalarArrayMin{
+		min: r.min,
+		err: r.err,
+	}
+}
+
+func (r ScalarArrayMin) withError(err Invalid) ScalarArrayRule {
+	r.err = err
+	return r
+}
+
+type ScalarArrayMax struct {
+	max int
+	err Invalid
+}
+
+func (r ScalarArrayMax) Validate(field Field, values []any, object typed.Typed, input typed.Typed, res *Result) []any {
+	if len(values) > r.max {
+		res.AddInvalidField(field, r.err)
+	}
+	return values
+}
+
+func (r ScalarArrayMax) clone() ScalarArrayRule {
+	return ScalarArrayMax{
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r ScalarArrayMax) withError(err Invalid) ScalarArrayRule {
+	r.err = err
+	return r
+}
+
+type ScalarArrayRange struct {
+	min int
+	max int
+	err Invalid
+}
+
+func (r ScalarArrayRange) Validate(field Field, values []any, object typed.Typed, input typed.Typed, res *Result) []any {
+	if len(values) < r.min || len(values) > r.max {
+		res.AddInvalidField(field, r.err)
+	}
+	return values
+}
+
+func (r ScalarArrayRange) clone() ScalarArrayRule {
+	return ScalarArrayRange{
+		min: r.min,
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r ScalarArrayRange) withError(err Invalid) ScalarArrayRule {
+	r.err = err
+	return r
+}
@@ -2,13 +2,14 @@ package validation
 
 import (
 	"regexp"
+	"unicode/utf8"
 
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils/typed"
 )
 
 type StringRule interface {
 	clone() StringRule
+	withError(err Invalid) StringRule
 	Validate(field Field, value string, object typed.Typed, input typed.Typed, res *Result) string
 }
 
@@ -23,16 +24,17 @@ func String() *StringValidator {
 }
 
 type StringValidator struct {
-	field     Field
-	dflt      string
-	required  bool
-	converter StringConverter
-	rules     []StringRule
-	errReq    Invalid
-	errType   Invalid
+	field      Field
+	dflt       string
+	required   bool
+	converter  StringConverter
+	transforms []func(string) string
+	rules      []StringRule
+	errReq     Invalid
+	errType    Invalid
 }
 
-func (v *StringValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
+func (v *StringValidator) argsToTyped(args *queryArgs, t typed.Typed) {
 	fieldName := v.field.Name
 	if value := args.Peek(fieldName); value != nil {
 		t[fieldName] = string(value)
@@ -56,6 +58,12 @@ func (v *StringValidator) validate(object typed.Typed, input typed.Typed, res *R
 		return
 	}
 
+	// transforms always run before rules, so that Length, Choice, etc
+	// see the normalized value
+	for _, transform := range v.transforms {
+		value = transform(value)
+	}
+
 	for _, rule := range v.rules {
 		value = rule.Validate(field, value, object, input, res)
 	}
@@ -76,13 +84,14 @@ func (v *StringValidator) addField(fieldName string) InputValidator {
 	}
 
 	return &StringValidator{
-		field:     field,
-		dflt:      v.dflt,
-		required:  v.required,
-		converter: v.converter,
-		rules:     rules,
-		errReq:    v.errReq,
-		errType:   v.errType,
+		field:      field,
+		dflt:       v.dflt,
+		required:   v.required,
+		converter:  v.converter,
+		transforms: v.transforms,
+		rules:      rules,
+		errReq:     v.errReq,
+		errType:    v.errType,
 	}
 }
 
@@ -96,6 +105,25 @@ func (v *StringValidator) Default(value string) *StringValidator {
 	return v
 }
 
+// Replaces the error added when the field is missing
+func (v *StringValidator) RequiredError(invalid Invalid) *StringValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *StringValidator) TypeError(invalid Invalid) *StringValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// String().Length(1, 20).RuleError(...)
+func (v *StringValidator) RuleError(invalid Invalid) *StringValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
 func (v *StringValidator) Choice(valid ...string) *StringValidator {
 	v.rules = append(v.rules, StringChoice{
 		valid: valid,
@@ -113,6 +141,16 @@ func (v *StringValidator) Length(min int, max int) *StringValidator {
 	return v
 }
 
+// Like Length, but counts runes rather than bytes
+func (v *StringValidator) RuneLength(min int, max int) *StringValidator {
+	v.rules = append(v.rules, StringRuneLen{
+		min: min,
+		max: max,
+		err: InvalidStringLength(min, max),
+	})
+	return v
+}
+
 func (v *StringValidator) Pattern(pattern string, errorMessage ...string) *StringValidator {
 	v.rules = append(v.rules, StringPattern{
 		pattern: regexp.MustCompile(pattern),
@@ -155,6 +193,41 @@ func (r StringLen) clone() StringRule {
 	}
 }
 
+func (r StringLen) withError(err Invalid) StringRule {
+	r.err = err
+	return r
+}
+
+type StringRuneLen struct {
+	min int
+	max int
+	err Invalid
+}
+
+func (r StringRuneLen) Validate(field Field, value string, object typed.Typed, input typed.Typed, res *Result) string {
+	l := utf8.RuneCountInString(value)
+	if min := r.min; min > 0 && l < min {
+		res.AddInvalidField(field, r.err)
+	}
+	if max := r.max; max > 0 && l > max {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r StringRuneLen) clone() StringRule {
+	return StringRuneLen{
+		min: r.min,
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r StringRuneLen) withError(err Invalid) StringRule {
+	r.err = err
+	return r
+}
+
 type StringPattern struct {
 	pattern *regexp.Regexp
 	err     Invalid
@@ -174,6 +247,11 @@ func (r StringPattern) clone() StringRule {
 	}
 }
 
+func (r StringPattern) withError(err Invalid) StringRule {
+	r.err = err
+	return r
+}
+
 type StringChoice struct {
 	valid []string
 	err   Invalid
@@ -196,6 +274,11 @@ func (r StringChoice) clone() StringRule {
 	}
 }
 
+func (r StringChoice) withError(err Invalid) StringRule {
+	r.err = err
+	return r
+}
+
 type StringFunc struct {
 	fn StringFuncValidator
 }
@@ -207,3 +290,9 @@ func (r StringFunc) Validate(field Field, value string, object typed.Typed, inpu
 func (r StringFunc) clone() StringRule {
 	return r
 }
+
+// Func rules add their own errors
+func (r StringFunc) withError(err Invalid) StringRule {
+	panicFuncRuleError()
+	return r
+}
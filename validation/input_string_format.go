@@ -0,0 +1,226 @@
+package validation
+
+/*
+Built-in string formats. Each is a StringFormat rule with its own error
+code (and a DataFormat naming the format), so, like any other rule,
+they can be given a custom error via RuleError:
+
+	String().Required().Email().RuleError(...)
+
+Date and DateTime also convert the value to a time.Time (replacing any
+converter given to Convert).
+*/
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"src.sqlkite.com/utils/typed"
+)
+
+const dateLayout = "2006-01-02"
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+// A single address, without a display name (e.g. "leto@sqlkite.com",
+// but not "Leto <leto@sqlkite.com>")
+func (v *StringValidator) Email() *StringValidator {
+	return v.format(isEmail, InvalidStringEmail())
+}
+
+// An absolute URL with one of the given schemes (http and https when
+// none are given)
+func (v *StringValidator) URL(schemes ...string) *StringValidator {
+	if len(schemes) == 0 {
+		schemes = []string{"http", "https"}
+	}
+	return v.format(func(value string) bool {
+		return isURL(value, schemes)
+	}, InvalidStringURL(schemes))
+}
+
+// An RFC 1123 hostname, e.g. "www.sqlkite.com" or "localhost"
+func (v *StringValidator) Hostname() *StringValidator {
+	return v.format(isHostname, InvalidStringHostname())
+}
+
+// An IPv4 or IPv6 address
+func (v *StringValidator) IP() *StringValidator {
+	return v.format(func(value string) bool {
+		_, err := netip.ParseAddr(value)
+		return err == nil
+	}, InvalidStringIP("ip"))
+}
+
+func (v *StringValidator) IPv4() *StringValidator {
+	return v.format(func(value string) bool {
+		ip, err := netip.ParseAddr(value)
+		return err == nil && ip.Is4()
+	}, InvalidStringIP("ipv4"))
+}
+
+func (v *StringValidator) IPv6() *StringValidator {
+	return v.format(func(value string) bool {
+		ip, err := netip.ParseAddr(value)
+		return err == nil && ip.Is6()
+	}, InvalidStringIP("ipv6"))
+}
+
+// An IPv4 or IPv6 CIDR block, e.g. "10.0.0.0/8"
+func (v *StringValidator) CIDR() *StringValidator {
+	return v.format(func(value string) bool {
+		_, err := netip.ParsePrefix(value)
+		return err == nil
+	}, InvalidStringCIDR())
+}
+
+// A YYYY-MM-DD date, converted to a time.Time (in UTC)
+func (v *StringValidator) Date() *StringValidator {
+	v.converter = timeConverter(dateLayout)
+	return v.format(func(value string) bool {
+		_, err := time.Parse(dateLayout, value)
+		return err == nil
+	}, InvalidStringDate())
+}
+
+// An RFC 3339 datetime, e.g. "2022-11-05T14:10:00Z", converted to a
+// time.Time
+func (v *StringValidator) DateTime() *StringValidator {
+	v.converter = timeConverter(time.RFC3339)
+	return v.format(func(value string) bool {
+		_, err := time.Parse(time.RFC3339, value)
+		return err == nil
+	}, InvalidStringDateTime())
+}
+
+// Standard or URL base64, with or without padding. The value isn't
+// decoded.
+func (v *StringValidator) Base64() *StringValidator {
+	return v.format(isBase64, InvalidStringBase64())
+}
+
+// The value isn't decoded
+func (v *StringValidator) Hex() *StringValidator {
+	return v.format(func(value string) bool {
+		_, err := hex.DecodeString(value)
+		return err == nil
+	}, InvalidStringHex())
+}
+
+// Lowercase letters and numbers, separated by single dashes, e.g.
+// "hello-world-2"
+func (v *StringValidator) Slug() *StringValidator {
+	return v.format(slugPattern.MatchString, InvalidStringSlug())
+}
+
+func (v *StringValidator) format(valid func(string) bool, err Invalid) *StringValidator {
+	v.rules = append(v.rules, StringFormat{
+		valid: valid,
+		err:   err,
+	})
+	return v
+}
+
+type StringFormat struct {
+	valid func(string) bool
+	err   Invalid
+}
+
+func (r StringFormat) Validate(field Field, value string, object typed.Typed, input typed.Typed, res *Result) string {
+	if !r.valid(value) {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r StringFormat) clone() StringRule {
+	return StringFormat{
+		valid: r.valid,
+		err:   r.err,
+	}
+}
+
+func (r StringFormat) withError(err Invalid) StringRule {
+	r.err = err
+	return r
+}
+
+// The StringFormat rule has already reported invalid values, which we
+// leave as-is.
+func timeConverter(layout string) StringConverter {
+	return func(field Field, value string, object typed.Typed, input typed.Typed, res *Result) any {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t
+		}
+		return value
+	}
+}
+
+func isEmail(value string) bool {
+	if len(value) > 254 {
+		return false
+	}
+	address, err := mail.ParseAddress(value)
+	return err == nil && address.Name == "" && address.Address == value
+}
+
+func isURL(value string, schemes []string) bool {
+	u, err := url.Parse(value)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	for _, scheme := range schemes {
+		if strings.EqualFold(u.Scheme, scheme) {
+			return true
+		}
+	}
+	return false
+}
+
+func isHostname(value string) bool {
+	if len(value) == 0 || len(value) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(value, ".") {
+		l := len(label)
+		if l == 0 || l > 63 || label[0] == '-' || label[l-1] == '-' {
+			return false
+		}
+		for i := 0; i < l; i++ {
+			c := label[i]
+			if !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') && !(c >= '0' && c <= '9') && c != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func isBase64(value string) bool {
+	if value == "" {
+		return true
+	}
+
+	var encoding *base64.Encoding
+	urlSafe := strings.ContainsAny(value, "-_")
+	padded := value[len(value)-1] == '='
+	switch {
+	case urlSafe && padded:
+		encoding = base64.URLEncoding
+	case urlSafe:
+		encoding = base64.RawURLEncoding
+	case padded:
+		encoding = base64.StdEncoding
+	default:
+		encoding = base64.RawStdEncoding
+	}
+	_, err := encoding.DecodeString(value)
+	return err == nil
+}
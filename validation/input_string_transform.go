@@ -0,0 +1,52 @@
+package validation
+
+/*
+Transforms normalize a string before it's validated. They run, in the
+order they were added, before any rule (regardless of when the rule was
+added), so:
+
+	String().Length(1, 20).Trim().Lower().Choice("admin", "user")
+
+checks the length and choice of the trimmed, lowercased value. The
+transformed value is what ends up in the input.
+*/
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Removes leading and trailing whitespace
+func (v *StringValidator) Trim() *StringValidator {
+	return v.transform(strings.TrimSpace)
+}
+
+func (v *StringValidator) Lower() *StringValidator {
+	return v.transform(strings.ToLower)
+}
+
+func (v *StringValidator) Upper() *StringValidator {
+	return v.transform(strings.ToUpper)
+}
+
+// Replaces every run of whitespace with a single space. Leading and
+// trailing whitespace is removed.
+func (v *StringValidator) CollapseWhitespace() *StringValidator {
+	return v.transform(collapseWhitespace)
+}
+
+// Unicode NFC normalization, so that, e.g., "é" written as "e" + a
+// combining accent is the same as the single "é" rune.
+func (v *StringValidator) NFC() *StringValidator {
+	return v.transform(norm.NFC.String)
+}
+
+func (v *StringValidator) transform(fn func(string) string) *StringValidator {
+	v.transforms = append(v.transforms, fn)
+	return v
+}
+
+func collapseWhitespace(value string) string {
+	return strings.Join(strings.Fields(value), " ")
+}
@@ -2,10 +2,14 @@ package validation
 
 import (
 	"encoding/hex"
+	"math"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils/json"
 	"src.sqlkite.com/utils/typed"
 )
 
@@ -681,6 +685,843 @@ func Test_Any_Func(t *testing.T) {
 	assert.Equal(t, data.Int("name"), 11)
 }
 
+func Test_ScalarArray_Type(t *testing.T) {
+	o := Object().
+		Field("tags", ScalarArray().Validator(String())).
+		Field("ids", ScalarArray().Required().Validator(Int()))
+
+	_, res := testInput(o)
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("tags").
+		Field("ids", Required())
+
+	_, res = testInput(o, "tags", "a", "ids", typed.Typed{})
+	assert.Validation(t, res).
+		Field("tags", InvalidArrayType()).
+		Field("ids", InvalidArrayType())
+
+	data, res := testInput(o, "tags", []any{"a", "b"}, "ids", []string{"1", "2"})
+	assert.Validation(t, res).FieldsHaveNoErrors("tags", "ids")
+	assert.List(t, data.Strings("tags"), []string{"a", "b"})
+	// converted by the Int validator
+	assert.List(t, data.Ints("ids"), []int{1, 2})
+}
+
+func Test_ScalarArray_Items(t *testing.T) {
+	tags := ScalarArray().Validator(String().Length(2, 3))
+	o := Object().
+		Field("tags", tags).Field("tags_clone", tags).
+		Field("ids", ScalarArray().Validator(Int().Min(1))).
+		Field("scores", ScalarArray().Validator(Float())).
+		Field("flags", ScalarArray().Validator(Bool())).
+		Field("uuids", ScalarArray().Validator(UUID()))
+
+	_, res := testInput(o,
+		"tags", []any{"ab", "a", "abc", 3},
+		"tags_clone", []any{"abcd"},
+		"ids", []any{1, 0, 2.0, "x"},
+		"scores", []any{1.5, true},
+		"flags", []any{true, "no"},
+		"uuids", []any{"7e9a7b8f-6b0c-4a36-8f6e-6a3c1e9b3c2d", "nope"},
+	)
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("tags.0", "tags.2", "ids.0", "ids.2", "scores.0", "flags.0", "uuids.0").
+		Field("tags.1", InvalidStringLength(2, 3)).
+		Field("tags.3", InvalidStringType()).
+		Field("tags_clone.0", InvalidStringLength(2, 3)).
+		Field("ids.1", InvalidIntMin(1)).
+		Field("ids.3", InvalidIntType()).
+		Field("scores.1", InvalidFloatType()).
+		Field("flags.1", InvalidBoolType()).
+		Field("uuids.1", InvalidUUIDType())
+}
+
+func Test_ScalarArray_MinMaxRange(t *testing.T) {
+	o := Object().
+		Field("a", ScalarArray().Min(2).Max(3)).
+		Field("b", ScalarArray().Range(2, 3))
+
+	_, res := testInput(o, "a", []any{1}, "b", []any{1, 2, 3, 4})
+	assert.Validation(t, res).
+		Field("a", InvalidArrayMinLength(2)).
+		Field("b", InvalidArrayRangeLength(2, 3))
+
+	_, res = testInput(o, "a", []any{1, 2, 3, 4}, "b", []any{1})
+	assert.Validation(t, res).
+		Field("a", InvalidArrayMaxLength(3)).
+		Field("b", InvalidArrayRangeLength(2, 3))
+
+	_, res = testInput(o, "a", []any{1, 2}, "b", []any{1, 2, 3})
+	assert.Validation(t, res).FieldsHaveNoErrors("a", "b")
+}
+
+func Test_ScalarArray_Unique(t *testing.T) {
+	o := Object().
+		Field("tags", ScalarArray().Unique().Validator(String().Length(1, 3))).
+		Field("ids", ScalarArray().Unique().Validator(Int())).
+		Field("any", ScalarArray().Unique())
+
+	_, res := testInput(o,
+		"tags", []any{"a", "b", "a", "abcd", "abcd", "b"},
+		"ids", []any{1, 1.0, "1", 2},
+		"any", []any{typed.Typed{}, typed.Typed{}, true, true},
+	)
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("tags.0", "tags.1", "ids.0", "ids.3", "any.0", "any.1", "any.2").
+		Field("tags.2", InvalidArrayUnique()).
+		Field("tags.3", InvalidStringLength(1, 3)).
+		Field("tags.4", InvalidStringLength(1, 3)).
+		Field("tags.5", InvalidArrayUnique()).
+		Field("ids.1", InvalidArrayUnique()).
+		Field("ids.2", InvalidArrayUnique()).
+		Field("any.3", InvalidArrayUnique())
+}
+
+func Test_ScalarArray_Default(t *testing.T) {
+	o := Object().Field("tags", ScalarArray().Default([]any{"a"}))
+	data, _ := testInput(o)
+	assert.List(t, data.Strings("tags"), []string{"a"})
+}
+
+func Test_ScalarArray_Nested(t *testing.T) {
+	child := Object().Field("tags", ScalarArray().Validator(String().Length(2, 3)))
+	o := Object().
+		Field("user", child).
+		Field("users", Array().Validator(child))
+
+	_, res := testInput(o,
+		"user", typed.Typed{"tags": []any{"ab", "a"}},
+		"users", []typed.Typed{
+			typed.Typed{"tags": []any{"ab"}},
+			typed.Typed{"tags": []any{"ab", "abc", "abcd"}},
+		},
+	)
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("user.tags.0", "users.0.tags.0", "users.1.tags.1").
+		Field("user.tags.1", InvalidStringLength(2, 3)).
+		Field("users.1.tags.2", InvalidStringLength(2, 3))
+}
+
+func Test_CustomErrors(t *testing.T) {
+	errReq := Invalid{Code: 9001, Error: "name please"}
+	errType := Invalid{Code: 9002, Error: "not a number"}
+	errMin := Invalid{Code: 9003, Error: "too young", Data: Min(18)}
+	errUnique := Invalid{Code: 9004, Error: "dupe"}
+
+	age := Int().TypeError(errType).Min(18).RuleError(errMin).Max(150)
+	o := Object().
+		Field("name", String().Required().RequiredError(errReq)).
+		Field("age", age).
+		Field("age2", age).
+		Field("tags", ScalarArray().Unique().UniqueError(errUnique).Min(1).RuleError(errMin))
+
+	_, res := testInput(o, "age", 17, "age2", "x", "tags", []any{"a", "a"})
+	assert.Validation(t, res).
+		Field("name", errReq).
+		FieldMessage("name", "name please").
+		Field("age", errMin).
+		Field("age2", errType).
+		Field("tags.1", errUnique)
+
+	// only the last rule is changed
+	_, res = testInput(o, "name", "leto", "age", 151, "tags", []any{})
+	assert.Validation(t, res).
+		Field("age", InvalidIntMax(150)).
+		Field("tags", errMin)
+}
+
+func Test_RuleError_WithoutRule(t *testing.T) {
+	defer func() {
+		assert.NotNil(t, recover())
+	}()
+	String().RuleError(Invalid{})
+}
+
+func Test_RuleError_AfterFunc(t *testing.T) {
+	assertPanics := func(fn func()) {
+		t.Helper()
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+		fn()
+	}
+
+	assertPanics(func() {
+		String().Func(func(field Field, value string, object typed.Typed, input typed.Typed, res *Result) string {
+			return value
+		}).RuleError(Invalid{})
+	})
+	assertPanics(func() {
+		Int().Func(func(field Field, value int, object typed.Typed, input typed.Typed, res *Result) int {
+			return value
+		}).RuleError(Invalid{})
+	})
+	assertPanics(func() {
+		Float().Func(func(field Field, value float64, object typed.Typed, input typed.Typed, res *Result) float64 {
+			return value
+		}).RuleError(Invalid{})
+	})
+	assertPanics(func() {
+		Time().Func(func(field Field, value time.Time, object typed.Typed, input typed.Typed, res *Result) time.Time {
+			return value
+		}).RuleError(Invalid{})
+	})
+	assertPanics(func() {
+		Duration().Func(func(field Field, value time.Duration, object typed.Typed, input typed.Typed, res *Result) time.Duration {
+			return value
+		}).RuleError(Invalid{})
+	})
+}
+
+func Test_Object_RequiredIf(t *testing.T) {
+	o := Object().
+		Field("type", String()).
+		Field("count", Int()).
+		Field("company", String()).
+		RequiredIf("company", "type", "business").
+		RequiredIf("type", "count", 2)
+
+	_, res := testInput(o, "type", "personal")
+	assert.Validation(t, res).FieldsHaveNoErrors("company", "type")
+
+	_, res = testInput(o, "type", "business")
+	assert.Validation(t, res).Field("company", Required())
+
+	_, res = testInput(o, "type", "business", "company", "x")
+	assert.Validation(t, res).FieldsHaveNoErrors("company")
+
+	// 2 from json is a float64
+	_, res = testInput(o, "count", float64(2))
+	assert.Validation(t, res).Field("type", Required())
+}
+
+func Test_Object_RequiredWith(t *testing.T) {
+	o := Object().RequiredWith("zip", "street", "city")
+
+	_, res := testInput(o)
+	assert.Validation(t, res).FieldsHaveNoErrors("zip")
+
+	_, res = testInput(o, "city", nil)
+	assert.Validation(t, res).FieldsHaveNoErrors("zip")
+
+	_, res = testInput(o, "city", "x")
+	assert.Validation(t, res).Field("zip", Required())
+
+	_, res = testInput(o, "city", "x", "zip", "1")
+	assert.Validation(t, res).FieldsHaveNoErrors("zip")
+}
+
+func Test_Object_EqualField(t *testing.T) {
+	o := Object().
+		Field("password", String()).
+		Field("confirm", String()).
+		EqualField("confirm", "password")
+
+	_, res := testInput(o, "password", "a")
+	assert.Validation(t, res).FieldsHaveNoErrors("confirm")
+
+	_, res = testInput(o, "password", "a", "confirm", "a")
+	assert.Validation(t, res).FieldsHaveNoErrors("confirm")
+
+	_, res = testInput(o, "password", "a", "confirm", "b")
+	assert.Validation(t, res).Field("confirm", InvalidFieldEqual("password"))
+
+	_, res = testInput(o, "confirm", "b")
+	assert.Validation(t, res).Field("confirm", InvalidFieldEqual("password"))
+}
+
+func Test_Object_GreaterThanField(t *testing.T) {
+	o := Object().
+		Field("min", Int()).
+		Field("max", Int()).
+		Field("start", String()).
+		Field("end", String()).
+		GreaterThanField("max", "min").
+		GreaterThanField("end", "start")
+
+	_, res := testInput(o, "min", 1, "max", 2, "start", "2022-01-01", "end", "2022-01-02")
+	assert.Validation(t, res).FieldsHaveNoErrors("max", "end")
+
+	_, res = testInput(o, "min", 2, "max", "2", "start", "2022-01-02", "end", "2022-01-02")
+	assert.Validation(t, res).
+		Field("max", InvalidFieldGreater("min")).
+		Field("end", InvalidFieldGreater("start"))
+
+	// missing and wrong types are left to the fields
+	_, res = testInput(o, "min", 2, "end", "2022-01-02", "start", 3)
+	assert.Validation(t, res).
+		Field("start", InvalidStringType()).
+		FieldsHaveNoErrors("max", "end")
+}
+
+func Test_Object_GreaterThanField_TimeValues(t *testing.T) {
+	o := Object().
+		GreaterThanField("end", "start").
+		GreaterThanField("max", "min")
+
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	_, res := testInput(o, "start", start, "end", start.Add(time.Second), "min", time.Second, "max", time.Minute)
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o, "start", start, "end", start, "min", time.Minute, "max", time.Second)
+	assert.Validation(t, res).
+		Field("end", InvalidFieldGreater("start")).
+		Field("max", InvalidFieldGreater("min"))
+}
+
+func Test_Object_FieldRules_Times(t *testing.T) {
+	o := Object().
+		Field("start", Time()).
+		Field("end", Time()).
+		Field("from", String().Date()).
+		Field("to", String().Date()).
+		Field("min", Duration()).
+		Field("max", Duration()).
+		Field("at", Time()).
+		Field("at_confirm", Time()).
+		GreaterThanField("end", "start").
+		GreaterThanField("to", "from").
+		GreaterThanField("max", "min").
+		EqualField("at_confirm", "at")
+
+	_, res := testInput(o,
+		"start", "2023-01-01T00:00:00Z", "end", "2023-01-02T00:00:00Z",
+		"from", "2023-01-01", "to", "2023-01-02",
+		"min", "1m", "max", "1h",
+		"at", "2023-01-01T01:00:00+01:00", "at_confirm", "2023-01-01T00:00:00Z")
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o,
+		"start", "2024-01-02T00:00:00Z", "end", "2023-01-01T00:00:00Z",
+		"from", "2023-01-02", "to", "2023-01-02",
+		"min", "1h", "max", "1m",
+		"at", "2023-01-01T00:00:00Z", "at_confirm", "2023-01-01T00:00:01Z")
+	assert.Validation(t, res).
+		Field("end", InvalidFieldGreater("start")).
+		Field("to", InvalidFieldGreater("from")).
+		Field("max", InvalidFieldGreater("min")).
+		Field("at_confirm", InvalidFieldEqual("at"))
+}
+
+func Test_Object_MutuallyExclusive(t *testing.T) {
+	o := Object().MutuallyExclusive("id", "email", "phone")
+	err := InvalidFieldsExclusive([]string{"id", "email", "phone"})
+
+	_, res := testInput(o)
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o, "email", "x", "id", nil)
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o, "id", 1, "email", "x", "phone", "y")
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("id").
+		Field("email", err).
+		Field("phone", err)
+}
+
+func Test_Object_OneOf(t *testing.T) {
+	o := Object().Field("filter", Object().OneOf("id", "email"))
+	err := InvalidFieldsOneOf([]string{"id", "email"})
+
+	// the rule doesn't apply when the object is missing
+	_, res := testInput(o)
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o, "filter", typed.Typed{"email": "x"})
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o, "filter", typed.Typed{})
+	assert.Validation(t, res).
+		Field("filter.id", err).
+		Field("filter.email", err)
+
+	_, res = testInput(o, "filter", typed.Typed{"id": 1, "email": "x"})
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("filter.id").
+		Field("filter.email", err)
+}
+
+func Test_Object_Rules_InArray(t *testing.T) {
+	o := Object().Field("users", Array().Validator(Object().
+		Field("password", String()).
+		EqualField("confirm", "password").
+		RuleError(Invalid{Code: 9001})))
+
+	_, res := testInput(o, "users", []typed.Typed{
+		{"password": "a", "confirm": "a"},
+		{"password": "a", "confirm": "b"},
+	})
+	assert.Validation(t, res).
+		FieldsHaveNoErrors("users.0.confirm").
+		Field("users.1.confirm", Invalid{Code: 9001})
+}
+
+func Test_Object_Unknown(t *testing.T) {
+	o := Object().
+		Field("name", String()).
+		Field("user", Object().Field("id", Int())).
+		RequiredWith("zip", "city")
+
+	// ignored by default
+	input, res := testInput(o, "name", "a", "other", 1, "user", typed.Typed{"x": 1})
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input.Int("other"), 1)
+	assert.Equal(t, input.Object("user").Int("x"), 1)
+
+	o.Strict()
+	input, res = testInput(o, "name", "a", "zip", "1", "city", "x", "b", 1, "a", 2, "user", typed.Typed{"x": 1})
+	assert.Validation(t, res).
+		Field("a", InvalidUnknownField()).
+		Field("b", InvalidUnknownField()).
+		FieldsHaveNoErrors("name", "zip", "city", "user", "user.x")
+	assert.Equal(t, input.Int("a"), 2)
+}
+
+func Test_Object_Strip(t *testing.T) {
+	o := Object().
+		Strip().
+		Field("name", String()).
+		Field("user", Object().Strict().UnknownError(Invalid{Code: 9001}).Field("id", Int()))
+
+	input, res := testInput(o, "name", "a", "other", 1, "user", typed.Typed{"id": 1, "x": 1})
+	assert.Validation(t, res).Field("user.x", Invalid{Code: 9001})
+	_, exists := input["other"]
+	assert.False(t, exists)
+	assert.Equal(t, input.Object("user").Int("x"), 1)
+}
+
+func Test_Object_Unknown_Args(t *testing.T) {
+	o := Object().
+		Strict().
+		Field("name", String()).
+		Field("user", Object().Strict().Field("id", Int())).
+		Field("tags", Object().Field("id", Int()))
+
+	input, res := testArgs(o, "name", "a", "foo", "1", "filter[x]", "y", "user[id]", "1", "user[role]", "x", "tags[x]", "1")
+	assert.Validation(t, res).
+		Field("foo", InvalidUnknownField()).
+		Field("filter", InvalidUnknownField()).
+		Field("user.role", InvalidUnknownField()).
+		FieldsHaveNoErrors("name", "user", "user.id", "tags", "tags.x")
+	assert.Equal(t, input.String("foo"), "1")
+	assert.Equal(t, input.Object("filter").String("x"), "y")
+
+	// otherwise, unknown keys are never copied into the input
+	o = Object().Strip().Field("name", String())
+	input, res = testArgs(o, "name", "a", "foo", "1")
+	assert.True(t, res.IsValid())
+	assert.Equal(t, len(input), 1)
+}
+
+func Test_Object_Unknown_InArray(t *testing.T) {
+	o := Object().Field("users", Array().Validator(Object().Strict().Field("id", Int())))
+	_, res := testInput(o, "users", []typed.Typed{{"id": 1}, {"id": 2, "name": "x"}})
+	assert.Validation(t, res).Field("users.1.name", InvalidUnknownField())
+
+	o = Object().Field("users", Array().Validator(Object().Strip().Field("id", Int())))
+	input, res := testInput(o, "users", []typed.Typed{{"id": 1, "name": "x"}})
+	assert.True(t, res.IsValid())
+	assert.Equal(t, len(input.Objects("users")[0]), 1)
+}
+
+func Test_String_Formats(t *testing.T) {
+	tests := []struct {
+		validator *StringValidator
+		err       Invalid
+		valid     []string
+		invalid   []string
+	}{
+		{String().Email(), InvalidStringEmail(),
+			[]string{"leto@sqlkite.com", "a.b+c@d"},
+			[]string{"", "leto", "@sqlkite.com", "Leto <leto@sqlkite.com>", "a@b, c@d"}},
+		{String().URL(), InvalidStringURL([]string{"http", "https"}),
+			[]string{"https://sqlkite.com", "HTTP://sqlkite.com:8080/a?b=c"},
+			[]string{"", "sqlkite.com", "/a/b", "ftp://sqlkite.com", "https://", "http://a b"}},
+		{String().URL("ftp"), InvalidStringURL([]string{"ftp"}),
+			[]string{"ftp://sqlkite.com"},
+			[]string{"https://sqlkite.com"}},
+		{String().Hostname(), InvalidStringHostname(),
+			[]string{"localhost", "www.sqlkite.com", "a-1.b2"},
+			[]string{"", "-a.com", "a-.com", "a..com", "a.com.", "a_b.com", strings.Repeat("a", 64) + ".com"}},
+		{String().IP(), InvalidStringIP("ip"),
+			[]string{"127.0.0.1", "::1", "2001:db8::68"},
+			[]string{"", "127.0.0", "1.2.3.4/8", "localhost"}},
+		{String().IPv4(), InvalidStringIP("ipv4"),
+			[]string{"127.0.0.1"},
+			[]string{"::1", "256.0.0.1"}},
+		{String().IPv6(), InvalidStringIP("ipv6"),
+			[]string{"::1", "::ffff:1.2.3.4"},
+			[]string{"127.0.0.1"}},
+		{String().CIDR(), InvalidStringCIDR(),
+			[]string{"10.0.0.0/8", "2001:db8::/32"},
+			[]string{"10.0.0.0", "10.0.0.0/33"}},
+		{String().Base64(), InvalidStringBase64(),
+			[]string{"", "aGk", "aGk=", "_-8", "_-8="},
+			[]string{"a", "aGk==", "a+_b", "!!!!"}},
+		{String().Hex(), InvalidStringHex(),
+			[]string{"", "00ff", "00FF"},
+			[]string{"0", "0g"}},
+		{String().Slug(), InvalidStringSlug(),
+			[]string{"a", "hello-world-2"},
+			[]string{"", "Hello", "a--b", "-a", "a-", "a_b"}},
+	}
+
+	for _, test := range tests {
+		o := Object().Field("f", test.validator)
+		for _, value := range test.valid {
+			_, res := testInput(o, "f", value)
+			if !res.IsValid() {
+				t.Errorf("expected %q to be valid (%s)", value, test.err.Error)
+			}
+		}
+		for _, value := range test.invalid {
+			_, res := testInput(o, "f", value)
+			assert.Validation(t, res).Field("f", test.err)
+		}
+	}
+}
+
+func Test_String_Formats_Data(t *testing.T) {
+	o := Object().
+		Field("url", String().URL("ws", "wss")).
+		Field("email", String().Email().RuleError(Invalid{Code: 9001}))
+
+	_, res := testInput(o, "url", "http://sqlkite.com", "email", "x")
+	assert.Validation(t, res).
+		FieldMessage("url", "must be a ws or wss url").
+		Field("email", Invalid{Code: 9001})
+
+	data, _ := json.Marshal(res.Errors()[0])
+	assert.Equal(t, string(data), `{"code":1028,"error":"must be a ws or wss url","data":{"format":"url","schemes":["ws","wss"]},"field":"url"}`)
+}
+
+func Test_String_Date(t *testing.T) {
+	o := Object().
+		Field("date", String().Date()).
+		Field("datetime", String().DateTime())
+
+	input, res := testInput(o, "date", "2022-11-05", "datetime", "2022-11-05T14:10:09.5+01:00")
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input["date"].(time.Time), time.Date(2022, 11, 5, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, input["datetime"].(time.Time).UTC(), time.Date(2022, 11, 5, 13, 10, 9, 500000000, time.UTC))
+
+	input, res = testInput(o, "date", "2022-02-30", "datetime", "2022-11-05 14:10:09")
+	assert.Validation(t, res).
+		Field("date", InvalidStringDate()).
+		Field("datetime", InvalidStringDateTime())
+	assert.Equal(t, input.String("date"), "2022-02-30")
+}
+
+func Test_String_Transforms(t *testing.T) {
+	o := Object().
+		Field("role", String().Choice("admin", "user").Trim().Lower()).
+		Field("code", String().Length(3, 3).Upper()).
+		Field("name", String().CollapseWhitespace().Length(0, 13)).
+		Field("nfc", String().NFC())
+
+	input, res := testInput(o, "role", " Admin\t", "code", "abc", "name", "  leto \n  atreides ", "nfc", "e\u0301")
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input.String("role"), "admin")
+	assert.Equal(t, input.String("code"), "ABC")
+	assert.Equal(t, input.String("name"), "leto atreides")
+	assert.Equal(t, input.String("nfc"), "\u00e9")
+
+	// still validated after the transform
+	_, res = testInput(o, "role", " other ", "name", "a"+strings.Repeat(" ", 20)+"b")
+	assert.Validation(t, res).
+		Field("role", InvalidStringChoice([]string{"admin", "user"})).
+		FieldsHaveNoErrors("name")
+}
+
+func Test_String_RuneLength(t *testing.T) {
+	o := Object().
+		Field("bytes", String().Length(0, 3)).
+		Field("runes", String().RuneLength(2, 3))
+
+	_, res := testInput(o, "bytes", "\u00e9\u00e9", "runes", "\u00e9\u00e9")
+	assert.Validation(t, res).
+		Field("bytes", InvalidStringLength(0, 3)).
+		FieldsHaveNoErrors("runes")
+
+	_, res = testInput(o, "runes", "\u00e9")
+	assert.Validation(t, res).Field("runes", InvalidStringLength(2, 3))
+
+	_, res = testInput(o, "runes", "abcd")
+	assert.Validation(t, res).Field("runes", InvalidStringLength(2, 3))
+}
+
+func Test_Time(t *testing.T) {
+	dflt := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	o := Object().
+		Field("req", Time().Required()).
+		Field("dflt", Time().Default(dflt))
+
+	input, res := testInput(o)
+	assert.Validation(t, res).Field("req", Required())
+	assert.Equal(t, input.Time("dflt"), dflt)
+
+	// json numbers are float64
+	input, res = testInput(o, "req", "2022-11-05T14:10:09Z", "dflt", float64(1667657409))
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input["req"].(time.Time), time.Date(2022, 11, 5, 14, 10, 9, 0, time.UTC))
+	assert.Equal(t, input["dflt"].(time.Time), time.Date(2022, 11, 5, 14, 10, 9, 0, time.UTC))
+
+	for _, value := range []any{"2022-11-05", true, 1.5, "now"} {
+		_, res = testInput(o, "req", value)
+		assert.Validation(t, res).Field("req", InvalidTimeType())
+	}
+}
+
+func Test_Time_Bounds(t *testing.T) {
+	min := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	max := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	o := Object().
+		Field("before", Time().Before(max)).
+		Field("after", Time().After(min)).
+		Field("between", Time().Between(min, max)).
+		Field("past", Time().BeforeNow(0)).
+		Field("future", Time().AfterNow(time.Minute)).
+		Field("recent", Time().BetweenNow(-time.Hour, 0))
+
+	now := time.Now()
+	_, res := testInput(o,
+		"before", "2022-12-31T23:59:59Z",
+		"after", "2022-01-01T00:00:01Z",
+		"between", "2022-06-01T00:00:00Z",
+		"past", now.Add(-time.Second),
+		"future", now.Add(time.Hour),
+		"recent", now.Add(-time.Minute))
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o,
+		"before", max,
+		"after", min,
+		"between", "2023-06-01T00:00:00Z",
+		"past", now.Add(time.Hour),
+		"future", now.Add(time.Second),
+		"recent", now.Add(-2*time.Hour))
+	assert.Validation(t, res).
+		Field("before", InvalidTimeBefore(max)).
+		Field("after", InvalidTimeAfter(min)).
+		Field("between", InvalidTimeRange(min, max)).
+		Field("past", InvalidTimeBeforeNow(0)).
+		FieldMessage("past", "must be before now").
+		Field("future", InvalidTimeAfterNow(time.Minute)).
+		FieldMessage("future", "must be after now+1m0s").
+		Field("recent", InvalidTimeRangeNow(-time.Hour, 0)).
+		FieldMessage("recent", "must be between now-1h0m0s and now")
+}
+
+func Test_Time_Args(t *testing.T) {
+	o := Object().
+		Field("since", Time()).
+		Field("until", Time())
+
+	input, res := testArgs(o, "since", "1667657409", "until", "2022-11-05T14:10:09+01:00")
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input["since"].(time.Time), time.Date(2022, 11, 5, 14, 10, 9, 0, time.UTC))
+	assert.Equal(t, input["until"].(time.Time).UTC(), time.Date(2022, 11, 5, 13, 10, 9, 0, time.UTC))
+
+	_, res = testArgs(o, "since", "yesterday")
+	assert.Validation(t, res).Field("since", InvalidTimeType())
+}
+
+func Test_Duration(t *testing.T) {
+	o := Object().
+		Field("req", Duration().Required()).
+		Field("dflt", Duration().Default(time.Minute)).
+		Field("min", Duration().Min(time.Second)).
+		Field("max", Duration().Max(time.Hour)).
+		Field("range", Duration().Range(time.Second, time.Hour))
+
+	input, res := testInput(o)
+	assert.Validation(t, res).Field("req", Required())
+	assert.Equal(t, input["dflt"].(time.Duration), time.Minute)
+
+	input, res = testInput(o, "req", "1h30m", "dflt", float64(90), "min", 1.5, "max", "1h", "range", 3600)
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input["req"].(time.Duration), 90*time.Minute)
+	assert.Equal(t, input["dflt"].(time.Duration), 90*time.Second)
+	assert.Equal(t, input["min"].(time.Duration), 1500*time.Millisecond)
+	assert.Equal(t, input["range"].(time.Duration), time.Hour)
+
+	_, res = testInput(o, "req", "soon", "min", "999ms", "max", "61m", "range", 0)
+	assert.Validation(t, res).
+		Field("req", InvalidDurationType()).
+		Field("min", InvalidDurationMin(time.Second)).
+		Field("max", InvalidDurationMax(time.Hour)).
+		Field("range", InvalidDurationRange(time.Second, time.Hour))
+
+	input, res = testArgs(o, "req", "5m", "min", "2.5")
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input["req"].(time.Duration), 5*time.Minute)
+	assert.Equal(t, input["min"].(time.Duration), 2500*time.Millisecond)
+}
+
+func Test_Map_Type(t *testing.T) {
+	o := Object().
+		Field("req", Map().Required()).
+		Field("dflt", Map().Default(map[string]any{"a": 1}))
+
+	input, res := testInput(o)
+	assert.Validation(t, res).Field("req", Required())
+	assert.Equal(t, input.Object("dflt").Int("a"), 1)
+
+	_, res = testInput(o, "req", []any{"a"}, "dflt", "a")
+	assert.Validation(t, res).
+		Field("req", InvalidMapType()).
+		Field("dflt", InvalidMapType())
+}
+
+func Test_Map_KeysAndValues(t *testing.T) {
+	o := Object().Field("labels", Map().
+		Key(String().Trim().Lower().Length(1, 5).Pattern("^[a-z]+$")).
+		Value(Int().Min(1)))
+
+	input, res := testInput(o, "labels", map[string]any{"env": 1, " TIER ": 2.0})
+	assert.True(t, res.IsValid())
+	labels := input.Object("labels")
+	assert.Equal(t, len(labels), 2)
+	assert.Equal(t, labels.Int("env"), 1)
+	assert.Equal(t, labels.Int("tier"), 2)
+
+	_, res = testInput(o, "labels", map[string]any{"env": 0, "a.b": 1, "toolong": "x"})
+	assert.Validation(t, res).
+		Field("labels.env", InvalidIntMin(1)).
+		Field("labels.a.b", InvalidStringPattern()).
+		Field("labels.toolong", InvalidStringLength(1, 5)).
+		Field("labels.toolong", InvalidIntType())
+}
+
+func Test_Map_DuplicateKeys(t *testing.T) {
+	o := Object().Field("labels", Map().Key(String().Lower()))
+
+	input, res := testInput(o, "labels", map[string]any{"ENV": "prod", "env": "dev", "Tier": "web"})
+	assert.Validation(t, res).Field("labels.env", InvalidMapDuplicateKey())
+	labels := input.Object("labels")
+	assert.Equal(t, len(labels), 2)
+	assert.Equal(t, labels.String("env"), "prod")
+	assert.Equal(t, labels.String("tier"), "web")
+
+	// a renamed key that sorts later isn't processed twice
+	o = Object().Field("labels", Map().
+		Key(String().Func(func(field Field, value string, object typed.Typed, input typed.Typed, res *Result) string {
+			return value + "z"
+		})).
+		Value(String().Func(func(field Field, value string, object typed.Typed, input typed.Typed, res *Result) string {
+			return value + "!"
+		})))
+	input, res = testInput(o, "labels", map[string]any{"a": "1", "b": "2"})
+	assert.True(t, res.IsValid())
+	labels = input.Object("labels")
+	assert.Equal(t, len(labels), 2)
+	assert.Equal(t, labels.String("az"), "1!")
+	assert.Equal(t, labels.String("bz"), "2!")
+}
+
+func Test_Map_Keys(t *testing.T) {
+	o := Object().
+		Field("min", Map().Min(2)).
+		Field("max", Map().Max(1)).
+		Field("range", Map().Range(1, 2).RuleError(Invalid{Code: 9001}))
+
+	_, res := testInput(o,
+		"min", map[string]any{"a": 1, "b": 2},
+		"max", map[string]any{"a": 1},
+		"range", map[string]any{"a": 1})
+	assert.True(t, res.IsValid())
+
+	_, res = testInput(o,
+		"min", map[string]any{"a": 1},
+		"max", map[string]any{"a": 1, "b": 2},
+		"range", map[string]any{})
+	assert.Validation(t, res).
+		Field("min", InvalidMapMinKeys(2)).
+		Field("max", InvalidMapMaxKeys(1)).
+		Field("range", Invalid{Code: 9001})
+}
+
+func Test_Map_Nested(t *testing.T) {
+	o := Object().Field("users", Array().Validator(Object().
+		Field("settings", Map().Value(Object().Field("enabled", Bool().Required())))))
+
+	_, res := testInput(o, "users", []typed.Typed{
+		{"settings": map[string]any{"email": map[string]any{"enabled": true}}},
+		{"settings": map[string]any{"sms": map[string]any{}, "push": map[string]any{"enabled": "x"}}},
+	})
+	assert.Validation(t, res).
+		Field("users.1.settings.sms.enabled", Required()).
+		Field("users.1.settings.push.enabled", InvalidBoolType()).
+		FieldsHaveNoErrors("users.0.settings.email.enabled")
+}
+
+func Test_Map_Args(t *testing.T) {
+	o := Object().
+		Field("labels", Map()).
+		Field("limits", Map().Value(Int().Max(10))).
+		Field("rules", Map().Value(Object().Field("min", Int())))
+
+	input, res := testQueryString(o, "labels[env]=prod&labels[tier]=web&limits[a]=1&limits[b]=11&rules[x][min]=3")
+	assert.Validation(t, res).Field("limits.b", InvalidIntMax(10))
+	assert.Equal(t, input.Object("labels").String("env"), "prod")
+	assert.Equal(t, input.Object("labels").String("tier"), "web")
+	assert.Equal(t, input.Object("limits").Int("a"), 1)
+	assert.Equal(t, input.Object("rules").Object("x").Int("min"), 3)
+}
+
+func Test_Duration_Overflow(t *testing.T) {
+	o := Object().Field("ttl", Duration().Max(time.Hour))
+
+	for _, value := range []any{1e300, -1e300, math.Inf(1), math.NaN(), 9223372037, int64(math.MaxInt64)} {
+		_, res := testInput(o, "ttl", value)
+		assert.Validation(t, res).Field("ttl", InvalidDurationType())
+	}
+
+	for _, value := range []string{"1e300", "Inf", "-Inf", "NaN", "9223372037"} {
+		_, res := testArgs(o, "ttl", value)
+		assert.Validation(t, res).Field("ttl", InvalidDurationType())
+	}
+
+	input, res := testInput(o, "ttl", 3600)
+	assert.True(t, res.IsValid())
+	assert.Equal(t, input["ttl"].(time.Duration), time.Hour)
+}
+
+func Test_Time_Overflow(t *testing.T) {
+	o := Object().Field("at", Time())
+	for _, value := range []any{1e300, math.Inf(1), math.Inf(-1), math.NaN()} {
+		_, res := testInput(o, "at", value)
+		assert.Validation(t, res).Field("at", InvalidTimeType())
+	}
+}
+
+func Test_Time_Range(t *testing.T) {
+	o := Object().Field("at", Time())
+
+	for _, value := range []any{float64(-62135596800), float64(253402300799), int64(253402300799), "9999-12-31T23:59:59Z"} {
+		input, res := testInput(o, "at", value)
+		assert.Validation(t, res).FieldsHaveNoErrors("at")
+		_, err := json.Marshal(input)
+		assert.Nil(t, err)
+	}
+	for _, value := range []any{float64(-62135596801), float64(253402300800), float64(253402300800000), int64(253402300800), "0000-12-31T23:59:59Z"} {
+		_, res := testInput(o, "at", value)
+		assert.Validation(t, res).Field("at", InvalidTimeType())
+	}
+
+	for _, value := range []string{"-62135596800", "253402300799"} {
+		input, res := testArgs(o, "at", value)
+		assert.Validation(t, res).FieldsHaveNoErrors("at")
+		_, err := json.Marshal(input)
+		assert.Nil(t, err)
+	}
+	for _, value := range []string{"-62135596801", "253402300800", "253402300800000"} {
+		_, res := testArgs(o, "at", value)
+		assert.Validation(t, res).Field("at", InvalidTimeType())
+	}
+}
+
 func testInput(o *ObjectValidator, args ...any) (typed.Typed, *Result) {
 	m := make(typed.Typed, len(args)/2)
 	for i := 0; i < len(args); i += 2 {
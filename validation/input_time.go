@@ -0,0 +1,266 @@
+package validation
+
+/*
+Times can be given as an RFC 3339 string or a unix timestamp (in
+seconds). Either way, a time.Time is stored in the input. Times outside
+of years 1 through 9999 are rejected as the wrong type.
+
+Before, After and Between take absolute times. BeforeNow, AfterNow and
+BetweenNow take offsets from the time of validation, e.g.
+
+	Time().AfterNow(0)                  // in the future
+	Time().BetweenNow(-24*time.Hour, 0) // within the last day
+*/
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	"src.sqlkite.com/utils"
+	"src.sqlkite.com/utils/typed"
+)
+
+const (
+	minUnixSeconds = -62135596800 // 0001-01-01T00:00:00Z
+	maxUnixSeconds = 253402300799 // 9999-12-31T23:59:59Z
+)
+
+type TimeRule interface {
+	clone() TimeRule
+	withError(err Invalid) TimeRule
+	Validate(field Field, value time.Time, object typed.Typed, input typed.Typed, res *Result) time.Time
+}
+
+func Time() *TimeValidator {
+	return &TimeValidator{
+		errReq:  Required(),
+		errType: InvalidTimeType(),
+	}
+}
+
+type TimeValidator struct {
+	field    Field
+	dflt     time.Time
+	required bool
+	rules    []TimeRule
+	errReq   Invalid
+	errType  Invalid
+}
+
+func (v *TimeValidator) argsToTyped(args *queryArgs, t typed.Typed) {
+	fieldName := v.field.Name
+	if value := args.Peek(fieldName); value != nil {
+		s := utils.B2S(value)
+		if n, err := strconv.ParseInt(s, 10, 64); err == nil && unixInRange(n) {
+			t[fieldName] = time.Unix(n, 0).UTC()
+		} else if tt, err := time.Parse(time.RFC3339, s); err == nil {
+			t[fieldName] = tt
+		} else {
+			t[fieldName] = value
+		}
+	}
+}
+
+func (v *TimeValidator) validate(object typed.Typed, input typed.Typed, res *Result) {
+	field := v.field
+	fieldName := field.Name
+	value, exists := timeIf(object, fieldName)
+
+	if !exists {
+		if _, exists := object[fieldName]; !exists {
+			if v.required {
+				res.AddInvalidField(field, v.errReq)
+			} else if dflt := v.dflt; !dflt.IsZero() {
+				object[fieldName] = dflt
+			}
+			return
+		}
+		res.AddInvalidField(field, v.errType)
+		return
+	}
+
+	for _, rule := range v.rules {
+		value = rule.Validate(field, value, object, input, res)
+	}
+	object[fieldName] = value
+}
+
+func (v *TimeValidator) addField(fieldName string) InputValidator {
+	field := v.field.add(fieldName)
+
+	rules := make([]TimeRule, len(v.rules))
+	for i, rule := range v.rules {
+		rules[i] = rule.clone()
+	}
+
+	return &TimeValidator{
+		field:    field,
+		dflt:     v.dflt,
+		required: v.required,
+		rules:    rules,
+		errReq:   v.errReq,
+		errType:  v.errType,
+	}
+}
+
+func (v *TimeValidator) Required() *TimeValidator {
+	v.required = true
+	return v
+}
+
+func (v *TimeValidator) Default(value time.Time) *TimeValidator {
+	v.dflt = value
+	return v
+}
+
+// Replaces the error added when the field is missing
+func (v *TimeValidator) RequiredError(invalid Invalid) *TimeValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *TimeValidator) TypeError(invalid Invalid) *TimeValidator {
+	v.errType = invalid
+	return v
+}
+
+// Replaces the error of the most recently added rule, e.g.
+// Time().AfterNow(0).RuleError(...)
+func (v *TimeValidator) RuleError(invalid Invalid) *TimeValidator {
+	setLastRuleError(v.rules, invalid)
+	return v
+}
+
+func (v *TimeValidator) Before(max time.Time) *TimeValidator {
+	v.rules = append(v.rules, TimeRange{
+		max: func() time.Time { return max },
+		err: InvalidTimeBefore(max),
+	})
+	return v
+}
+
+func (v *TimeValidator) After(min time.Time) *TimeValidator {
+	v.rules = append(v.rules, TimeRange{
+		min: func() time.Time { return min },
+		err: InvalidTimeAfter(min),
+	})
+	return v
+}
+
+func (v *TimeValidator) Between(min time.Time, max time.Time) *TimeValidator {
+	v.rules = append(v.rules, TimeRange{
+		min: func() time.Time { return min },
+		max: func() time.Time { return max },
+		err: InvalidTimeRange(min, max),
+	})
+	return v
+}
+
+func (v *TimeValidator) BeforeNow(offset time.Duration) *TimeValidator {
+	v.rules = append(v.rules, TimeRange{
+		max: fromNow(offset),
+		err: InvalidTimeBeforeNow(offset),
+	})
+	return v
+}
+
+func (v *TimeValidator) AfterNow(offset time.Duration) *TimeValidator {
+	v.rules = append(v.rules, TimeRange{
+		min: fromNow(offset),
+		err: InvalidTimeAfterNow(offset),
+	})
+	return v
+}
+
+func (v *TimeValidator) BetweenNow(from time.Duration, to time.Duration) *TimeValidator {
+	v.rules = append(v.rules, TimeRange{
+		min: fromNow(from),
+		max: fromNow(to),
+		err: InvalidTimeRangeNow(from, to),
+	})
+	return v
+}
+
+func (v *TimeValidator) Func(fn func(field Field, value time.Time, object typed.Typed, input typed.Typed, res *Result) time.Time) *TimeValidator {
+	v.rules = append(v.rules, TimeFunc{fn: fn})
+	return v
+}
+
+// Bounds are functions so that relative bounds are computed on every
+// validation. A nil bound is unbounded. Bounds are exclusive.
+type TimeRange struct {
+	min func() time.Time
+	max func() time.Time
+	err Invalid
+}
+
+func (r TimeRange) Validate(field Field, value time.Time, object typed.Typed, input typed.Typed, res *Result) time.Time {
+	if (r.min != nil && !value.After(r.min())) || (r.max != nil && !value.Before(r.max())) {
+		res.AddInvalidField(field, r.err)
+	}
+	return value
+}
+
+func (r TimeRange) clone() TimeRule {
+	return TimeRange{
+		min: r.min,
+		max: r.max,
+		err: r.err,
+	}
+}
+
+func (r TimeRange) withError(err Invalid) TimeRule {
+	r.err = err
+	return r
+}
+
+type TimeFunc struct {
+	fn func(Field, time.Time, typed.Typed, typed.Typed, *Result) time.Time
+}
+
+func (r TimeFunc) Validate(field Field, value time.Time, object typed.Typed, input typed.Typed, res *Result) time.Time {
+	return r.fn(field, value, object, input, res)
+}
+
+func (r TimeFunc) clone() TimeRule {
+	return r
+}
+
+// Func rules add their own errors
+func (r TimeFunc) withError(err Invalid) TimeRule {
+	panicFuncRuleError()
+	return r
+}
+
+func fromNow(offset time.Duration) func() time.Time {
+	return func() time.Time {
+		return time.Now().Add(offset)
+	}
+}
+
+// typed.TimeIf, plus whole float64s, which is what JSON gives us for a
+// unix timestamp. Only times between years 1 and 9999 are accepted:
+// anything else can't be marshalled back to JSON (and NaN, Inf or huge
+// floats would overflow).
+func timeIf(object typed.Typed, fieldName string) (time.Time, bool) {
+	var t time.Time
+	if f, ok := object[fieldName].(float64); ok {
+		if f != math.Trunc(f) || f < minUnixSeconds || f > maxUnixSeconds {
+			return time.Time{}, false
+		}
+		t = time.Unix(int64(f), 0).UTC()
+	} else if t, ok = object.TimeIf(fieldName); !ok {
+		return time.Time{}, false
+	}
+
+	if !unixInRange(t.Unix()) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+func unixInRange(n int64) bool {
+	return n >= minUnixSeconds && n <= maxUnixSeconds
+}
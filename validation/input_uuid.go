@@ -1,7 +1,6 @@
 package validation
 
 import (
-	"github.com/valyala/fasthttp"
 	"src.sqlkite.com/utils/typed"
 	"src.sqlkite.com/utils/uuid"
 )
@@ -21,7 +20,7 @@ type UUIDValidator struct {
 	errType  Invalid
 }
 
-func (v *UUIDValidator) argsToTyped(args *fasthttp.Args, t typed.Typed) {
+func (v *UUIDValidator) argsToTyped(args *queryArgs, t typed.Typed) {
 	fieldName := v.field.Name
 	if value := args.Peek(fieldName); value != nil {
 		t[fieldName] = string(value)
@@ -70,3 +69,15 @@ func (v *UUIDValidator) Default(value string) *UUIDValidator {
 	v.dflt = value
 	return v
 }
+
+// Replaces the error added when the field is missing
+func (v *UUIDValidator) RequiredError(invalid Invalid) *UUIDValidator {
+	v.errReq = invalid
+	return v
+}
+
+// Replaces the error added when the value is the wrong type
+func (v *UUIDValidator) TypeError(invalid Invalid) *UUIDValidator {
+	v.errType = invalid
+	return v
+}
@@ -0,0 +1,96 @@
+package validation
+
+/*
+Invalid.Error is an english message. For other languages, a Catalog
+renders the message from the Invalid's Code and Data:
+
+	catalog := NewCatalog()
+	catalog.Add("fr", utils.VAL_REQUIRED, "obligatoire")
+	catalog.Add("fr", utils.VAL_INT_RANGE, "doit être entre {{.Min}} et {{.Max}}")
+
+	...
+	res.Localize(catalog, "fr-CA")
+
+Messages are text/template templates executed against Invalid.Data (so
+{{.Min}} for a DataRange or DataMin, {{.Valid}} for a DataChoice). A
+locale like "fr-CA" falls back to "fr", and errors without a message
+in the catalog keep their existing (english) message.
+*/
+
+import (
+	"strings"
+	"text/template"
+)
+
+type Catalog interface {
+	// Returns false if the catalog has no message for the invalid's code
+	// in the given locale.
+	Message(locale string, invalid Invalid) (string, bool)
+}
+
+type TemplateCatalog struct {
+	locales map[string]map[uint32]*template.Template
+}
+
+func NewCatalog() *TemplateCatalog {
+	return &TemplateCatalog{
+		locales: make(map[string]map[uint32]*template.Template),
+	}
+}
+
+// Not thread-safe, the catalog should be loaded on startup.
+func (c *TemplateCatalog) Add(locale string, code uint32, message string) error {
+	tmpl, err := template.New("").Option("missingkey=zero").Parse(message)
+	if err != nil {
+		return err
+	}
+
+	locale = strings.ToLower(locale)
+	messages := c.locales[locale]
+	if messages == nil {
+		messages = make(map[uint32]*template.Template)
+		c.locales[locale] = messages
+	}
+	messages[code] = tmpl
+	return nil
+}
+
+func (c *TemplateCatalog) Message(locale string, invalid Invalid) (string, bool) {
+	tmpl := c.lookup(strings.ToLower(locale), invalid.Code)
+	if tmpl == nil {
+		return "", false
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, invalid.Data); err != nil {
+		return "", false
+	}
+	return sb.String(), true
+}
+
+func (c *TemplateCatalog) lookup(locale string, code uint32) *template.Template {
+	for {
+		if tmpl, ok := c.locales[locale][code]; ok {
+			return tmpl
+		}
+		i := strings.LastIndexAny(locale, "-_")
+		if i == -1 {
+			return nil
+		}
+		locale = locale[:i]
+	}
+}
+
+// Replaces the message of every error with the catalog's message for
+// the locale (when it has one).
+func (r *Result) Localize(catalog Catalog, locale string) {
+	errors := r.errors[:r.len]
+	for i, err := range errors {
+		if invalid, ok := err.(InvalidField); ok {
+			if message, ok := catalog.Message(locale, invalid.Invalid); ok {
+				invalid.Error = message
+				errors[i] = invalid
+			}
+		}
+	}
+}
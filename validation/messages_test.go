@@ -0,0 +1,50 @@
+package validation
+
+import (
+	"testing"
+
+	"src.sqlkite.com/tests/assert"
+	"src.sqlkite.com/utils"
+)
+
+func Test_TemplateCatalog_Message(t *testing.T) {
+	c := NewCatalog()
+	assert.Nil(t, c.Add("fr", utils.VAL_REQUIRED, "obligatoire"))
+	assert.Nil(t, c.Add("fr", utils.VAL_INT_RANGE, "doit être entre {{.Min}} et {{.Max}}"))
+	assert.Nil(t, c.Add("fr-CA", utils.VAL_REQUIRED, "requis"))
+	assert.NotNil(t, c.Add("fr", utils.VAL_INT_MIN, "{{.Min"))
+
+	m, ok := c.Message("fr", Required())
+	assert.True(t, ok)
+	assert.Equal(t, m, "obligatoire")
+
+	m, ok = c.Message("fr", InvalidIntRange(1, 10))
+	assert.True(t, ok)
+	assert.Equal(t, m, "doit être entre 1 et 10")
+
+	// most specific locale wins, falls back to the language
+	m, _ = c.Message("fr-ca", Required())
+	assert.Equal(t, m, "requis")
+	m, _ = c.Message("fr_BE", InvalidIntRange(2, 3))
+	assert.Equal(t, m, "doit être entre 2 et 3")
+
+	_, ok = c.Message("fr", InvalidIntMin(1))
+	assert.False(t, ok)
+	_, ok = c.Message("de", Required())
+	assert.False(t, ok)
+}
+
+func Test_Result_Localize(t *testing.T) {
+	c := NewCatalog()
+	c.Add("fr", utils.VAL_STRING_LEN, "entre {{.Min}} et {{.Max}} caractères")
+
+	o := Object().
+		Field("name", String().Required()).
+		Field("title", String().Length(2, 5))
+
+	_, res := testInput(o, "title", "x")
+	res.Localize(c, "fr")
+	assert.Validation(t, res).
+		FieldMessage("name", "required").
+		FieldMessage("title", "entre 2 et 5 caractères")
+}
@@ -11,6 +11,10 @@ type Result struct {
 	pool         *Pool
 	arrayIndexes []int
 	arrayCount   int
+
+	// Maps use the same placeholders as arrays, but with a key instead of
+	// an index. The index is set to -1 when the key should be used.
+	arrayKeys []string
 }
 
 func NewResult(maxErrors uint16) *Result {
@@ -18,6 +22,7 @@ func NewResult(maxErrors uint16) *Result {
 		arrayCount:   -1,
 		errors:       make([]any, maxErrors),
 		arrayIndexes: make([]int, 10),
+		arrayKeys:    make([]string, 10),
 	}
 }
 
@@ -49,7 +54,11 @@ func (r *Result) AddInvalidField(field Field, invalid Invalid) {
 		for _, part := range field.Path {
 			w.WriteByte('.')
 			if part == "" {
-				w.WriteString(strconv.Itoa(indexes[indexIndex]))
+				if index := indexes[indexIndex]; index == -1 {
+					w.WriteString(r.arrayKeys[indexIndex])
+				} else {
+					w.WriteString(strconv.Itoa(index))
+				}
 				indexIndex += 1
 			} else {
 				w.WriteString(part)
@@ -83,6 +92,11 @@ func (r *Result) arrayIndex(i int) {
 	r.arrayIndexes[r.arrayCount] = i
 }
 
+func (r *Result) mapKey(key string) {
+	r.arrayIndexes[r.arrayCount] = -1
+	r.arrayKeys[r.arrayCount] = key
+}
+
 func (r *Result) endArray() {
 	r.arrayCount -= 1
 }
@@ -2,6 +2,8 @@ package validation
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"src.sqlkite.com/utils"
 )
@@ -52,6 +54,97 @@ func InvalidStringChoice(choices []string) Invalid {
 	}
 }
 
+func InvalidStringEmail() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_EMAIL,
+		Error: "must be an email address",
+		Data:  Format("email"),
+	}
+}
+
+func InvalidStringURL(schemes []string) Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_URL,
+		Error: fmt.Sprintf("must be a %s url", strings.Join(schemes, " or ")),
+		Data: DataURL{
+			Format:  "url",
+			Schemes: schemes,
+		},
+	}
+}
+
+func InvalidStringHostname() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_HOSTNAME,
+		Error: "must be a hostname",
+		Data:  Format("hostname"),
+	}
+}
+
+// format is one of "ip", "ipv4" or "ipv6"
+func InvalidStringIP(format string) Invalid {
+	err := "must be an ip address"
+	switch format {
+	case "ipv4":
+		err = "must be an ipv4 address"
+	case "ipv6":
+		err = "must be an ipv6 address"
+	}
+	return Invalid{
+		Code:  utils.VAL_STRING_IP,
+		Error: err,
+		Data:  Format(format),
+	}
+}
+
+func InvalidStringCIDR() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_CIDR,
+		Error: "must be a cidr block",
+		Data:  Format("cidr"),
+	}
+}
+
+func InvalidStringDate() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_DATE,
+		Error: "must be a date (YYYY-MM-DD)",
+		Data:  Format("date"),
+	}
+}
+
+func InvalidStringDateTime() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_DATETIME,
+		Error: "must be an RFC 3339 datetime",
+		Data:  Format("datetime"),
+	}
+}
+
+func InvalidStringBase64() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_BASE64,
+		Error: "must be base64 encoded",
+		Data:  Format("base64"),
+	}
+}
+
+func InvalidStringHex() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_HEX,
+		Error: "must be hex encoded",
+		Data:  Format("hex"),
+	}
+}
+
+func InvalidStringSlug() Invalid {
+	return Invalid{
+		Code:  utils.VAL_STRING_SLUG,
+		Error: "must only contain lowercase letters, numbers and dashes",
+		Data:  Format("slug"),
+	}
+}
+
 func InvalidIntType() Invalid {
 	return Invalid{
 		Code:  utils.VAL_INT_TYPE,
@@ -128,6 +221,180 @@ func InvalidArrayRangeLength(min int, max int) Invalid {
 	}
 }
 
+func InvalidArrayUnique() Invalid {
+	return Invalid{
+		Code:  utils.VAL_ARRAY_UNIQUE,
+		Error: "must be unique",
+	}
+}
+
+func InvalidTimeType() Invalid {
+	return Invalid{
+		Code:  utils.VAL_TIME_TYPE,
+		Error: "must be an RFC 3339 datetime or unix timestamp",
+	}
+}
+
+func InvalidTimeBefore(max time.Time) Invalid {
+	return Invalid{
+		Code:  utils.VAL_TIME_BEFORE,
+		Error: fmt.Sprintf("must be before %s", max.Format(time.RFC3339)),
+		Data:  Max(max),
+	}
+}
+
+func InvalidTimeAfter(min time.Time) Invalid {
+	return Invalid{
+		Code:  utils.VAL_TIME_AFTER,
+		Error: fmt.Sprintf("must be after %s", min.Format(time.RFC3339)),
+		Data:  Min(min),
+	}
+}
+
+func InvalidTimeRange(min time.Time, max time.Time) Invalid {
+	return Invalid{
+		Code:  utils.VAL_TIME_RANGE,
+		Error: fmt.Sprintf("must be between %s and %s", min.Format(time.RFC3339), max.Format(time.RFC3339)),
+		Data:  Range(min, max),
+	}
+}
+
+// Relative to the current time, e.g. "now+1h0m0s"
+func InvalidTimeBeforeNow(offset time.Duration) Invalid {
+	max := relativeToNow(offset)
+	return Invalid{
+		Code:  utils.VAL_TIME_BEFORE,
+		Error: fmt.Sprintf("must be before %s", max),
+		Data:  Max(max),
+	}
+}
+
+func InvalidTimeAfterNow(offset time.Duration) Invalid {
+	min := relativeToNow(offset)
+	return Invalid{
+		Code:  utils.VAL_TIME_AFTER,
+		Error: fmt.Sprintf("must be after %s", min),
+		Data:  Min(min),
+	}
+}
+
+func InvalidTimeRangeNow(from time.Duration, to time.Duration) Invalid {
+	min, max := relativeToNow(from), relativeToNow(to)
+	return Invalid{
+		Code:  utils.VAL_TIME_RANGE,
+		Error: fmt.Sprintf("must be between %s and %s", min, max),
+		Data:  Range(min, max),
+	}
+}
+
+func InvalidDurationType() Invalid {
+	return Invalid{
+		Code:  utils.VAL_DURATION_TYPE,
+		Error: "must be a duration",
+	}
+}
+
+func InvalidDurationMin(min time.Duration) Invalid {
+	return Invalid{
+		Code:  utils.VAL_DURATION_MIN,
+		Error: fmt.Sprintf("must be at least %s", min),
+		Data:  Min(min.String()),
+	}
+}
+
+func InvalidDurationMax(max time.Duration) Invalid {
+	return Invalid{
+		Code:  utils.VAL_DURATION_MAX,
+		Error: fmt.Sprintf("must be at most %s", max),
+		Data:  Max(max.String()),
+	}
+}
+
+func InvalidDurationRange(min time.Duration, max time.Duration) Invalid {
+	return Invalid{
+		Code:  utils.VAL_DURATION_RANGE,
+		Error: fmt.Sprintf("must be between %s and %s", min, max),
+		Data:  Range(min.String(), max.String()),
+	}
+}
+
+func InvalidFieldEqual(other string) Invalid {
+	return Invalid{
+		Code:  utils.VAL_FIELD_EQUAL,
+		Error: fmt.Sprintf("must match %s", other),
+		Data:  Fields(other),
+	}
+}
+
+func InvalidFieldGreater(other string) Invalid {
+	return Invalid{
+		Code:  utils.VAL_FIELD_GREATER,
+		Error: fmt.Sprintf("must be greater than %s", other),
+		Data:  Fields(other),
+	}
+}
+
+func InvalidFieldsExclusive(fields []string) Invalid {
+	return Invalid{
+		Code:  utils.VAL_FIELDS_EXCLUSIVE,
+		Error: fmt.Sprintf("only one of %s can be given", strings.Join(fields, ", ")),
+		Data:  Fields(fields...),
+	}
+}
+
+func InvalidFieldsOneOf(fields []string) Invalid {
+	return Invalid{
+		Code:  utils.VAL_FIELDS_ONE_OF,
+		Error: fmt.Sprintf("exactly one of %s is required", strings.Join(fields, ", ")),
+		Data:  Fields(fields...),
+	}
+}
+
+func InvalidUnknownField() Invalid {
+	return Invalid{
+		Code:  utils.VAL_UNKNOWN_FIELD,
+		Error: "is not a valid field",
+	}
+}
+
+func InvalidMapType() Invalid {
+	return Invalid{
+		Code:  utils.VAL_MAP_TYPE,
+		Error: "must be an object",
+	}
+}
+
+func InvalidMapMinKeys(min int) Invalid {
+	return Invalid{
+		Code:  utils.VAL_MAP_MIN_KEYS,
+		Error: fmt.Sprintf("must have at least %d keys", min),
+		Data:  Min(min),
+	}
+}
+
+func InvalidMapMaxKeys(max int) Invalid {
+	return Invalid{
+		Code:  utils.VAL_MAP_MAX_KEYS,
+		Error: fmt.Sprintf("must have no more than %d keys", max),
+		Data:  Max(max),
+	}
+}
+
+func InvalidMapRangeKeys(min int, max int) Invalid {
+	return Invalid{
+		Code:  utils.VAL_MAP_RANGE_KEYS,
+		Error: fmt.Sprintf("must have between %d and %d keys", min, max),
+		Data:  Range(min, max),
+	}
+}
+
+func InvalidMapDuplicateKey() Invalid {
+	return Invalid{
+		Code:  utils.VAL_MAP_DUPLICATE_KEY,
+		Error: "is a duplicate of another key",
+	}
+}
+
 func InvalidFloatType() Invalid {
 	return Invalid{
 		Code:  utils.VAL_FLOAT_TYPE,
@@ -215,3 +482,38 @@ func Choice[T any](valid []T) any {
 		Valid: valid,
 	}
 }
+
+type DataFormat struct {
+	Format string `json:"format"`
+}
+
+func Format(format string) any {
+	return DataFormat{
+		Format: format,
+	}
+}
+
+type DataURL struct {
+	Format  string   `json:"format"`
+	Schemes []string `json:"schemes"`
+}
+
+func relativeToNow(offset time.Duration) string {
+	switch {
+	case offset > 0:
+		return "now+" + offset.String()
+	case offset < 0:
+		return "now" + offset.String()
+	}
+	return "now"
+}
+
+type DataFields struct {
+	Fields []string `json:"fields"`
+}
+
+func Fields(fields ...string) any {
+	return DataFields{
+		Fields: fields,
+	}
+}